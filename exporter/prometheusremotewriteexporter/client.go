@@ -0,0 +1,222 @@
+// Copyright 2020 The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheusremotewriteexporter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/prompb"
+	"go.uber.org/zap"
+
+	"go.opentelemetry.io/collector/config/confighttp"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+)
+
+// tenantClient is the HTTP client and endpoint a single tenant's (or, for
+// single-tenant setups, the exporter's) write requests are sent through. A
+// dnssrv+/dnssrvnoa+/dns+ endpoint carries a resolver instead of a static
+// endpoint, re-resolved in the background and round-robined across on Next().
+type tenantClient struct {
+	client   *http.Client
+	endpoint string
+	resolver *endpointResolver
+	headers  map[string]string
+}
+
+// newTenantClient builds the http.Client dedicated to settings, starting a
+// background endpointResolver when settings.Endpoint requests service
+// discovery.
+func newTenantClient(ctx context.Context, settings confighttp.HTTPClientSettings, headers map[string]string, dnsRefreshInterval time.Duration, logger *zap.Logger) (*tenantClient, error) {
+	client, err := settings.ToClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build HTTP client for endpoint %q: %w", settings.Endpoint, err)
+	}
+
+	resolver, ok, err := newResolverForEndpoint(ctx, settings.Endpoint, dnsRefreshInterval, logger)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return &tenantClient{client: client, endpoint: settings.Endpoint, headers: headers}, nil
+	}
+	return &tenantClient{client: client, resolver: resolver, headers: headers}, nil
+}
+
+// target returns the endpoint to send the next request to: the next
+// round-robined backend for a service-discovery endpoint, or the static
+// endpoint otherwise.
+func (c *tenantClient) target() string {
+	if c.resolver != nil {
+		return c.resolver.Next()
+	}
+	return c.endpoint
+}
+
+// close stops c's background resolver, if it has one.
+func (c *tenantClient) close() {
+	if c.resolver != nil {
+		c.resolver.Stop()
+	}
+}
+
+// send marshals req, snappy-compresses it, and POSTs it to c's target
+// endpoint, setting tenantHeader (if non-empty) to tenant.
+func (c *tenantClient) send(ctx context.Context, req *prompb.WriteRequest, tenantHeader, tenant string) error {
+	data, err := proto.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to marshal write request: %w", err)
+	}
+	body := snappy.Encode(nil, data)
+
+	target := c.target()
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build request for endpoint %q: %w", target, err)
+	}
+
+	httpReq.Header.Set("Content-Encoding", "snappy")
+	httpReq.Header.Set("Content-Type", "application/x-protobuf")
+	httpReq.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	if tenantHeader != "" && tenant != "" {
+		httpReq.Header.Set(tenantHeader, tenant)
+	}
+	for k, v := range c.headers {
+		httpReq.Header.Set(k, v)
+	}
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to send write request to %q: %w", target, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("remote write to %q returned HTTP %d", target, resp.StatusCode)
+	}
+	return nil
+}
+
+// prwClient dispatches a batch of per-tenant write requests, using a
+// dedicated tenantClient for any tenant with a Tenants.Endpoints override and
+// a single default tenantClient for everyone else.
+type prwClient struct {
+	tenants            *TenantSettings
+	dnsRefreshInterval time.Duration
+	logger             *zap.Logger
+	defaultClient      *tenantClient
+
+	mu            sync.Mutex
+	tenantClients map[string]*tenantClient
+}
+
+// newPRWClient builds the default client from cfg's top-level
+// HTTPClientSettings; per-tenant override clients are built lazily since most
+// configurations only ever exercise a handful of the possible tenants.
+func newPRWClient(ctx context.Context, cfg *Config, logger *zap.Logger) (*prwClient, error) {
+	defaultClient, err := newTenantClient(ctx, cfg.HTTPClientSettings, cfg.Headers, cfg.DNSRefreshInterval, logger)
+	if err != nil {
+		return nil, err
+	}
+	return &prwClient{
+		tenants:            cfg.Tenants,
+		dnsRefreshInterval: cfg.DNSRefreshInterval,
+		logger:             logger,
+		defaultClient:      defaultClient,
+	}, nil
+}
+
+// clientFor returns the tenantClient to use for tenant, building and caching
+// its override client the first time tenant is seen.
+func (p *prwClient) clientFor(ctx context.Context, tenant string) (*tenantClient, error) {
+	if p.tenants == nil {
+		return p.defaultClient, nil
+	}
+	settings, ok := p.tenants.endpointFor(tenant)
+	if !ok {
+		return p.defaultClient, nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if c, ok := p.tenantClients[tenant]; ok {
+		return c, nil
+	}
+	c, err := newTenantClient(ctx, settings, p.defaultClient.headers, p.dnsRefreshInterval, p.logger)
+	if err != nil {
+		return nil, err
+	}
+	if p.tenantClients == nil {
+		p.tenantClients = make(map[string]*tenantClient)
+	}
+	p.tenantClients[tenant] = c
+	return c, nil
+}
+
+// Close stops the background resolver of every client p has built.
+func (p *prwClient) Close() {
+	p.defaultClient.close()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, c := range p.tenantClients {
+		c.close()
+	}
+}
+
+// pushWriteRequests dispatches one WriteRequest per tenant in parallel,
+// through each tenant's dedicated client where configured, tagging every
+// request with its tenant via the configured header.
+func (p *prwClient) pushWriteRequests(ctx context.Context, requests map[string]*prompb.WriteRequest) error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+
+	var headerName string
+	if p.tenants != nil {
+		headerName = p.tenants.headerName()
+	}
+
+	for tenant, req := range requests {
+		tenant, req := tenant, req
+
+		client, err := p.clientFor(ctx, tenant)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := client.send(ctx, req, headerName, tenant); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+	return consumererror.Combine(errs)
+}
@@ -19,6 +19,9 @@
 package prometheusremotewriteexporter
 
 import (
+	"fmt"
+	"time"
+
 	"go.opentelemetry.io/collector/config/confighttp"
 	"go.opentelemetry.io/collector/config/configmodels"
 	"go.opentelemetry.io/collector/exporter/exporterhelper"
@@ -40,4 +43,47 @@ type Config struct {
 	Headers map[string]string `mapstructure:"headers"`
 
 	HTTPClientSettings confighttp.HTTPClientSettings `mapstructure:"http_setting"`
+
+	// DNSRefreshInterval controls how often a dnssrv+/dnssrvnoa+/dns+
+	// Endpoint is re-resolved. Defaults to 30s. Ignored for plain endpoints.
+	DNSRefreshInterval time.Duration `mapstructure:"dns_refresh_interval"`
+
+	// Tenants configures multi-tenant routing, sending each tenant's time
+	// series to a dedicated Cortex/Mimir endpoint and tagging the request
+	// with the tenant's X-Scope-OrgID. Leave unset for single-tenant setups.
+	Tenants *TenantSettings `mapstructure:"tenants"`
+}
+
+// TenantSettings configures per-tenant routing for environments with
+// multiple Cortex/Mimir tenants behind a single pipeline.
+type TenantSettings struct {
+	// AttributeKey is the resource attribute whose value identifies the
+	// tenant a given time series belongs to, e.g. "service.namespace".
+	// Metric and data point attributes are not consulted.
+	AttributeKey string `mapstructure:"attribute_key"`
+
+	// HeaderName is the HTTP header used to carry the resolved tenant ID.
+	// Defaults to "X-Scope-OrgID".
+	HeaderName string `mapstructure:"header_name"`
+
+	// Default is the tenant ID used when AttributeKey is absent from a
+	// given time series' resource.
+	Default string `mapstructure:"default"`
+
+	// Endpoints optionally routes a tenant's write requests to a dedicated
+	// endpoint and HTTP client, instead of the exporter's top-level
+	// HTTPClientSettings. Keyed by resolved tenant ID.
+	Endpoints map[string]confighttp.HTTPClientSettings `mapstructure:"endpoints"`
+}
+
+// ValidateTenants checks that AttributeKey is set whenever a Tenants section
+// is present; without it there is no way to resolve a time series' tenant.
+func (cfg *Config) ValidateTenants() error {
+	if cfg.Tenants == nil {
+		return nil
+	}
+	if cfg.Tenants.AttributeKey == "" {
+		return fmt.Errorf("tenants.attribute_key must be set when a tenants section is present")
+	}
+	return nil
 }
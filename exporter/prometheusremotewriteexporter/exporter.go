@@ -0,0 +1,187 @@
+// Copyright 2020 The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/prometheus/prompb"
+	"go.uber.org/zap"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer/pdata"
+)
+
+// prwExporter converts incoming OTLP metrics into Prometheus remote-write
+// time series and pushes them through a tenant-aware, service-discovery-aware
+// prwClient.
+type prwExporter struct {
+	namespace string
+	tenants   *TenantSettings
+	client    *prwClient
+	logger    *zap.Logger
+}
+
+// newPRWExporter validates cfg and builds the prwExporter that will serve it.
+func newPRWExporter(ctx context.Context, cfg *Config, logger *zap.Logger) (*prwExporter, error) {
+	if err := cfg.ValidateTenants(); err != nil {
+		return nil, err
+	}
+
+	client, err := newPRWClient(ctx, cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return &prwExporter{namespace: cfg.Namespace, tenants: cfg.Tenants, client: client, logger: logger}, nil
+}
+
+func (e *prwExporter) Start(context.Context, component.Host) error {
+	return nil
+}
+
+func (e *prwExporter) Shutdown(context.Context) error {
+	e.client.Close()
+	return nil
+}
+
+// ConsumeMetrics converts md to prompb.TimeSeries, grouping them into one
+// WriteRequest per resolved tenant when e.tenants is configured (a single,
+// untenanted WriteRequest otherwise), and dispatches them through e.client.
+func (e *prwExporter) ConsumeMetrics(ctx context.Context, md pdata.Metrics) error {
+	requests := make(map[string]*prompb.WriteRequest)
+
+	rms := md.ResourceMetrics()
+	for i := 0; i < rms.Len(); i++ {
+		rm := rms.At(i)
+
+		series, err := e.timeSeriesForResource(rm)
+		if err != nil {
+			return err
+		}
+		if len(series) == 0 {
+			continue
+		}
+
+		if e.tenants != nil {
+			e.tenants.groupByTenant(rm.Resource().Attributes(), series, requests)
+			continue
+		}
+
+		req, ok := requests[""]
+		if !ok {
+			req = &prompb.WriteRequest{}
+			requests[""] = req
+		}
+		req.Timeseries = append(req.Timeseries, series...)
+	}
+
+	if len(requests) == 0 {
+		return nil
+	}
+	return e.client.pushWriteRequests(ctx, requests)
+}
+
+// timeSeriesForResource converts every numeric data point under rm into a
+// prompb.TimeSeries, labelled with the resource's attributes, the data
+// point's own labels, and a __name__ label built from the metric's name
+// (prefixed with e.namespace, if set).
+func (e *prwExporter) timeSeriesForResource(rm pdata.ResourceMetrics) ([]*prompb.TimeSeries, error) {
+	resourceLabels := labelsFromAttributes(rm.Resource().Attributes())
+
+	var out []*prompb.TimeSeries
+	ilms := rm.InstrumentationLibraryMetrics()
+	for i := 0; i < ilms.Len(); i++ {
+		metrics := ilms.At(i).Metrics()
+		for j := 0; j < metrics.Len(); j++ {
+			metric := metrics.At(j)
+			name := e.metricName(metric.Name())
+
+			series, err := seriesForMetric(metric, name, resourceLabels)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, series...)
+		}
+	}
+	return out, nil
+}
+
+func (e *prwExporter) metricName(name string) string {
+	if e.namespace == "" {
+		return name
+	}
+	return e.namespace + "_" + name
+}
+
+// seriesForMetric converts the data points of a single metric into
+// TimeSeries. Only the numeric, non-distribution types are supported.
+func seriesForMetric(metric pdata.Metric, name string, resourceLabels []prompb.Label) ([]*prompb.TimeSeries, error) {
+	switch metric.DataType() {
+	case pdata.MetricDataTypeIntGauge:
+		return intPointsToSeries(name, resourceLabels, metric.IntGauge().DataPoints()), nil
+	case pdata.MetricDataTypeDoubleGauge:
+		return doublePointsToSeries(name, resourceLabels, metric.DoubleGauge().DataPoints()), nil
+	case pdata.MetricDataTypeIntSum:
+		return intPointsToSeries(name, resourceLabels, metric.IntSum().DataPoints()), nil
+	case pdata.MetricDataTypeDoubleSum:
+		return doublePointsToSeries(name, resourceLabels, metric.DoubleSum().DataPoints()), nil
+	default:
+		return nil, fmt.Errorf("metric %q has unsupported data type %v for remote write", name, metric.DataType())
+	}
+}
+
+func intPointsToSeries(name string, resourceLabels []prompb.Label, points pdata.IntDataPointSlice) []*prompb.TimeSeries {
+	out := make([]*prompb.TimeSeries, 0, points.Len())
+	for i := 0; i < points.Len(); i++ {
+		p := points.At(i)
+		out = append(out, newTimeSeries(name, resourceLabels, p.LabelsMap(), float64(p.Value()), p.Timestamp()))
+	}
+	return out
+}
+
+func doublePointsToSeries(name string, resourceLabels []prompb.Label, points pdata.DoubleDataPointSlice) []*prompb.TimeSeries {
+	out := make([]*prompb.TimeSeries, 0, points.Len())
+	for i := 0; i < points.Len(); i++ {
+		p := points.At(i)
+		out = append(out, newTimeSeries(name, resourceLabels, p.LabelsMap(), p.Value(), p.Timestamp()))
+	}
+	return out
+}
+
+func newTimeSeries(name string, resourceLabels []prompb.Label, pointLabels pdata.StringMap, value float64, ts pdata.Timestamp) *prompb.TimeSeries {
+	labels := make([]prompb.Label, 0, len(resourceLabels)+pointLabels.Len()+1)
+	labels = append(labels, prompb.Label{Name: "__name__", Value: name})
+	labels = append(labels, resourceLabels...)
+	pointLabels.Range(func(k, v string) bool {
+		labels = append(labels, prompb.Label{Name: k, Value: v})
+		return true
+	})
+
+	return &prompb.TimeSeries{
+		Labels:  labels,
+		Samples: []prompb.Sample{{Value: value, Timestamp: int64(ts) / 1e6}},
+	}
+}
+
+func labelsFromAttributes(attrs pdata.AttributeMap) []prompb.Label {
+	var labels []prompb.Label
+	attrs.Range(func(k string, v pdata.AttributeValue) bool {
+		labels = append(labels, prompb.Label{Name: k, Value: v.StringVal()})
+		return true
+	})
+	return labels
+}
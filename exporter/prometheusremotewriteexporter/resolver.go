@@ -0,0 +1,239 @@
+// Copyright 2020 The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	schemeDNSSRV    = "dnssrv+"
+	schemeDNSSRVNoA = "dnssrvnoa+"
+	schemeDNS       = "dns+"
+
+	defaultDNSRefreshInterval = 30 * time.Second
+)
+
+// resolverKind identifies which service-discovery mechanism, if any, an
+// endpoint requested through its scheme prefix.
+type resolverKind int
+
+const (
+	resolverNone resolverKind = iota
+	resolverDNSSRV
+	resolverDNSSRVNoA
+	resolverDNS
+)
+
+// parseDiscoveryEndpoint strips a dnssrv+/dnssrvnoa+/dns+ prefix from
+// endpoint, if present, and reports which resolverKind it requested along
+// with the remaining, directly-usable URL.
+func parseDiscoveryEndpoint(endpoint string) (resolverKind, string) {
+	switch {
+	case strings.HasPrefix(endpoint, schemeDNSSRV):
+		return resolverDNSSRV, strings.TrimPrefix(endpoint, schemeDNSSRV)
+	case strings.HasPrefix(endpoint, schemeDNSSRVNoA):
+		return resolverDNSSRVNoA, strings.TrimPrefix(endpoint, schemeDNSSRVNoA)
+	case strings.HasPrefix(endpoint, schemeDNS):
+		return resolverDNS, strings.TrimPrefix(endpoint, schemeDNS)
+	default:
+		return resolverNone, endpoint
+	}
+}
+
+// netResolver is the subset of *net.Resolver used by endpointResolver, so
+// tests can substitute a fake implementation that returns changing results.
+type netResolver interface {
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// endpointResolver periodically re-resolves a dnssrv+/dnssrvnoa+/dns+
+// endpoint and round-robins requests across the resulting backend URLs. On a
+// refresh failure it logs a warning and keeps using the last successful set.
+type endpointResolver struct {
+	kind     resolverKind
+	rawURL   string
+	interval time.Duration
+	resolver netResolver
+	logger   *zap.Logger
+
+	mu       sync.Mutex
+	next     int
+	backends []string
+
+	doneChan chan struct{}
+}
+
+// newEndpointResolver creates an endpointResolver and performs the initial
+// resolution synchronously, so callers get an immediately-usable pool or a
+// clear startup error. Pass a nil resolver to use net.DefaultResolver.
+func newEndpointResolver(kind resolverKind, rawURL string, interval time.Duration, resolver netResolver, logger *zap.Logger) (*endpointResolver, error) {
+	if interval <= 0 {
+		interval = defaultDNSRefreshInterval
+	}
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	r := &endpointResolver{
+		kind:     kind,
+		rawURL:   rawURL,
+		interval: interval,
+		resolver: resolver,
+		logger:   logger,
+		doneChan: make(chan struct{}),
+	}
+
+	if err := r.refresh(context.Background()); err != nil {
+		return nil, fmt.Errorf("initial resolution of %q failed: %w", rawURL, err)
+	}
+
+	return r, nil
+}
+
+// newResolverForEndpoint inspects endpoint for a dnssrv+/dnssrvnoa+/dns+
+// prefix and, if present, returns a started *endpointResolver for it; ok is
+// false for a plain endpoint, which needs no resolver at all.
+func newResolverForEndpoint(ctx context.Context, endpoint string, refreshInterval time.Duration, logger *zap.Logger) (r *endpointResolver, ok bool, err error) {
+	kind, rawURL := parseDiscoveryEndpoint(endpoint)
+	if kind == resolverNone {
+		return nil, false, nil
+	}
+
+	r, err = newEndpointResolver(kind, rawURL, refreshInterval, nil, logger)
+	if err != nil {
+		return nil, false, err
+	}
+	r.Start(ctx)
+	return r, true, nil
+}
+
+func (r *endpointResolver) refresh(ctx context.Context) error {
+	u, err := url.Parse(r.rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid endpoint %q: %w", r.rawURL, err)
+	}
+
+	var hosts []string
+	switch r.kind {
+	case resolverDNSSRV, resolverDNSSRVNoA:
+		_, srvs, err := r.resolver.LookupSRV(ctx, "", "", u.Host)
+		if err != nil {
+			return err
+		}
+		for _, srv := range srvs {
+			target := strings.TrimSuffix(srv.Target, ".")
+			if r.kind == resolverDNSSRVNoA {
+				hosts = append(hosts, fmt.Sprintf("%s:%d", target, srv.Port))
+				continue
+			}
+			addrs, err := r.resolver.LookupHost(ctx, target)
+			if err != nil {
+				return err
+			}
+			for _, addr := range addrs {
+				hosts = append(hosts, fmt.Sprintf("%s:%d", addr, srv.Port))
+			}
+		}
+	case resolverDNS:
+		addrs, err := r.resolver.LookupHost(ctx, u.Hostname())
+		if err != nil {
+			return err
+		}
+		port := u.Port()
+		for _, addr := range addrs {
+			if port != "" {
+				hosts = append(hosts, net.JoinHostPort(addr, port))
+			} else {
+				hosts = append(hosts, addr)
+			}
+		}
+	default:
+		return fmt.Errorf("refresh called on a non-discovery endpoint %q", r.rawURL)
+	}
+
+	if len(hosts) == 0 {
+		return fmt.Errorf("no backends resolved for %q", r.rawURL)
+	}
+
+	backends := make([]string, len(hosts))
+	for i, host := range hosts {
+		backend := *u
+		backend.Host = host
+		backends[i] = backend.String()
+	}
+
+	r.mu.Lock()
+	r.backends = backends
+	r.mu.Unlock()
+	return nil
+}
+
+// Start begins periodically refreshing the backend pool every r.interval
+// until ctx is done or Stop is called.
+func (r *endpointResolver) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := r.refresh(ctx); err != nil {
+					r.logger.Warn("Failed to refresh backend pool, keeping last resolved set",
+						zap.String("endpoint", r.rawURL), zap.Error(err))
+				}
+			case <-r.doneChan:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop terminates the periodic refresh loop.
+func (r *endpointResolver) Stop() {
+	close(r.doneChan)
+}
+
+// Next round-robins across the currently resolved backends.
+func (r *endpointResolver) Next() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	backend := r.backends[r.next%len(r.backends)]
+	r.next++
+	return backend
+}
+
+// Backends returns a snapshot of the currently resolved backend URLs.
+func (r *endpointResolver) Backends() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([]string, len(r.backends))
+	copy(out, r.backends)
+	return out
+}
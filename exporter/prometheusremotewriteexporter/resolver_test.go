@@ -0,0 +1,161 @@
+// Copyright 2020 The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeResolver is a netResolver whose results can be mutated across ticks so
+// tests can observe the pool updating.
+type fakeResolver struct {
+	mu    sync.Mutex
+	srv   map[string][]*net.SRV
+	hosts map[string][]string
+	err   error
+}
+
+func (f *fakeResolver) LookupSRV(_ context.Context, _, _, name string) (string, []*net.SRV, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return "", nil, f.err
+	}
+	return "", f.srv[name], nil
+}
+
+func (f *fakeResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.hosts[host], nil
+}
+
+func (f *fakeResolver) setHosts(host string, addrs []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.hosts[host] = addrs
+}
+
+func (f *fakeResolver) setErr(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.err = err
+}
+
+func TestParseDiscoveryEndpoint(t *testing.T) {
+	kind, rest := parseDiscoveryEndpoint("dnssrv+https://_http._tcp.example.com/api/v1/write")
+	assert.Equal(t, resolverDNSSRV, kind)
+	assert.Equal(t, "https://_http._tcp.example.com/api/v1/write", rest)
+
+	kind, rest = parseDiscoveryEndpoint("dnssrvnoa+https://_http._tcp.example.com/api/v1/write")
+	assert.Equal(t, resolverDNSSRVNoA, kind)
+	assert.Equal(t, "https://_http._tcp.example.com/api/v1/write", rest)
+
+	kind, rest = parseDiscoveryEndpoint("dns+https://foo.example.com:9090/api/v1/write")
+	assert.Equal(t, resolverDNS, kind)
+	assert.Equal(t, "https://foo.example.com:9090/api/v1/write", rest)
+
+	kind, rest = parseDiscoveryEndpoint("https://foo.example.com/api/v1/write")
+	assert.Equal(t, resolverNone, kind)
+	assert.Equal(t, "https://foo.example.com/api/v1/write", rest)
+}
+
+func TestEndpointResolver_DNSRoundRobinAndRefresh(t *testing.T) {
+	fr := &fakeResolver{hosts: map[string][]string{"example.com": {"10.0.0.1", "10.0.0.2"}}}
+
+	r, err := newEndpointResolver(resolverDNS, "https://example.com:9090/api/v1/write", 5*time.Millisecond, fr, zap.NewNop())
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{"https://10.0.0.1:9090/api/v1/write", "https://10.0.0.2:9090/api/v1/write"}, r.Backends())
+
+	first := r.Next()
+	second := r.Next()
+	assert.NotEqual(t, first, second)
+	assert.Equal(t, first, r.Next())
+
+	r.Start(context.Background())
+	defer r.Stop()
+
+	fr.setHosts("example.com", []string{"10.0.0.3"})
+	require.Eventually(t, func() bool {
+		backends := r.Backends()
+		return len(backends) == 1 && backends[0] == "https://10.0.0.3:9090/api/v1/write"
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestEndpointResolver_KeepsLastGoodSetOnFailure(t *testing.T) {
+	fr := &fakeResolver{hosts: map[string][]string{"example.com": {"10.0.0.1"}}}
+
+	r, err := newEndpointResolver(resolverDNS, "https://example.com/api/v1/write", 5*time.Millisecond, fr, zap.NewNop())
+	require.NoError(t, err)
+
+	r.Start(context.Background())
+	defer r.Stop()
+
+	fr.setErr(errors.New("boom"))
+	time.Sleep(30 * time.Millisecond)
+
+	assert.Equal(t, []string{"https://10.0.0.1/api/v1/write"}, r.Backends())
+}
+
+func TestEndpointResolver_SRV(t *testing.T) {
+	fr := &fakeResolver{
+		srv: map[string][]*net.SRV{
+			"_http._tcp.example.com": {
+				{Target: "a.example.com.", Port: 80},
+				{Target: "b.example.com.", Port: 80},
+			},
+		},
+		hosts: map[string][]string{
+			"a.example.com": {"10.0.0.1"},
+			"b.example.com": {"10.0.0.2"},
+		},
+	}
+
+	r, err := newEndpointResolver(resolverDNSSRV, "http://_http._tcp.example.com/write", time.Hour, fr, zap.NewNop())
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"http://10.0.0.1:80/write", "http://10.0.0.2:80/write"}, r.Backends())
+}
+
+func TestEndpointResolver_SRVNoA(t *testing.T) {
+	fr := &fakeResolver{
+		srv: map[string][]*net.SRV{
+			"_http._tcp.example.com": {{Target: "a.example.com.", Port: 80}},
+		},
+	}
+
+	r, err := newEndpointResolver(resolverDNSSRVNoA, "http://_http._tcp.example.com/write", time.Hour, fr, zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"http://a.example.com:80/write"}, r.Backends())
+}
+
+func TestNewResolverForEndpoint_PlainEndpointNeedsNoResolver(t *testing.T) {
+	r, ok, err := newResolverForEndpoint(context.Background(), "https://example.com/write", 0, zap.NewNop())
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Nil(t, r)
+}
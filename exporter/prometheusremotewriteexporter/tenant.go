@@ -0,0 +1,65 @@
+// Copyright 2020 The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheusremotewriteexporter
+
+import (
+	"github.com/prometheus/prometheus/prompb"
+
+	"go.opentelemetry.io/collector/config/confighttp"
+	"go.opentelemetry.io/collector/consumer/pdata"
+)
+
+const defaultTenantHeaderName = "X-Scope-OrgID"
+
+// headerName returns the HTTP header used to carry the resolved tenant ID,
+// defaulting to X-Scope-OrgID.
+func (t *TenantSettings) headerName() string {
+	if t.HeaderName != "" {
+		return t.HeaderName
+	}
+	return defaultTenantHeaderName
+}
+
+// resolveTenant returns the tenant ID for a resource, reading t.AttributeKey
+// from attrs and falling back to t.Default when the attribute is absent.
+func (t *TenantSettings) resolveTenant(attrs pdata.AttributeMap) string {
+	if v, ok := attrs.Get(t.AttributeKey); ok {
+		return v.StringVal()
+	}
+	return t.Default
+}
+
+// endpointFor returns the HTTPClientSettings dedicated to tenant, or
+// ok=false when no such override is configured and the exporter's top-level
+// HTTPClientSettings should be used instead.
+func (t *TenantSettings) endpointFor(tenant string) (confighttp.HTTPClientSettings, bool) {
+	settings, ok := t.Endpoints[tenant]
+	return settings, ok
+}
+
+// groupByTenant resolves the tenant for resourceAttrs and appends series to
+// that tenant's *prompb.WriteRequest within requests, creating it if needed.
+// Callers iterate their resources and call this once per resource, building
+// up one write request per tenant across the whole batch.
+func (t *TenantSettings) groupByTenant(resourceAttrs pdata.AttributeMap, series []*prompb.TimeSeries, requests map[string]*prompb.WriteRequest) {
+	tenant := t.resolveTenant(resourceAttrs)
+
+	req, ok := requests[tenant]
+	if !ok {
+		req = &prompb.WriteRequest{}
+		requests[tenant] = req
+	}
+	req.Timeseries = append(req.Timeseries, series...)
+}
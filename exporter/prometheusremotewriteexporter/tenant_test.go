@@ -0,0 +1,89 @@
+// Copyright 2020 The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheusremotewriteexporter
+
+import (
+	"testing"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.opentelemetry.io/collector/config/confighttp"
+	"go.opentelemetry.io/collector/consumer/pdata"
+)
+
+func attrsWithNamespace(ns string) pdata.AttributeMap {
+	attrs := pdata.NewAttributeMap()
+	if ns != "" {
+		attrs.InsertString("service.namespace", ns)
+	}
+	return attrs
+}
+
+func TestTenantSettings_HeaderName(t *testing.T) {
+	assert.Equal(t, defaultTenantHeaderName, (&TenantSettings{}).headerName())
+	assert.Equal(t, "X-Custom-Tenant", (&TenantSettings{HeaderName: "X-Custom-Tenant"}).headerName())
+}
+
+func TestTenantSettings_ResolveTenant(t *testing.T) {
+	tenants := &TenantSettings{AttributeKey: "service.namespace", Default: "fallback"}
+
+	assert.Equal(t, "team-a", tenants.resolveTenant(attrsWithNamespace("team-a")))
+	assert.Equal(t, "fallback", tenants.resolveTenant(attrsWithNamespace("")))
+}
+
+func TestTenantSettings_EndpointFor(t *testing.T) {
+	tenants := &TenantSettings{
+		Endpoints: map[string]confighttp.HTTPClientSettings{
+			"team-a": {Endpoint: "https://team-a.example.com/api/v1/write"},
+		},
+	}
+
+	settings, ok := tenants.endpointFor("team-a")
+	require.True(t, ok)
+	assert.Equal(t, "https://team-a.example.com/api/v1/write", settings.Endpoint)
+
+	_, ok = tenants.endpointFor("team-b")
+	assert.False(t, ok)
+}
+
+func TestTenantSettings_GroupByTenant(t *testing.T) {
+	tenants := &TenantSettings{AttributeKey: "service.namespace", Default: "default"}
+	requests := make(map[string]*prompb.WriteRequest)
+
+	seriesA := []*prompb.TimeSeries{{Labels: []prompb.Label{{Name: "__name__", Value: "a"}}}}
+	seriesB := []*prompb.TimeSeries{{Labels: []prompb.Label{{Name: "__name__", Value: "b"}}}}
+
+	tenants.groupByTenant(attrsWithNamespace("team-a"), seriesA, requests)
+	tenants.groupByTenant(attrsWithNamespace("team-a"), seriesB, requests)
+	tenants.groupByTenant(attrsWithNamespace(""), seriesB, requests)
+
+	require.Contains(t, requests, "team-a")
+	require.Contains(t, requests, "default")
+	assert.Len(t, requests["team-a"].Timeseries, 2)
+	assert.Len(t, requests["default"].Timeseries, 1)
+}
+
+func TestConfig_ValidateTenants(t *testing.T) {
+	var cfg Config
+	assert.NoError(t, cfg.ValidateTenants())
+
+	cfg.Tenants = &TenantSettings{}
+	assert.Error(t, cfg.ValidateTenants())
+
+	cfg.Tenants.AttributeKey = "service.namespace"
+	assert.NoError(t, cfg.ValidateTenants())
+}
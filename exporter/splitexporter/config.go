@@ -0,0 +1,74 @@
+// Copyright 2020 The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package splitexporter implements an exporter that fans traces, metrics,
+// and logs out to other, already-configured exporters, so a single pipeline
+// entry can send different signals - or different slices of the same signal
+// - to different backends. See Config for the routing rules.
+package splitexporter
+
+import (
+	"fmt"
+
+	"go.opentelemetry.io/collector/config/configmodels"
+)
+
+// RouteConfig references another configured exporter by ID. Match, if set,
+// is a regular expression; a route with no Match is the catch-all default
+// for its signal.
+type RouteConfig struct {
+	// Exporter is the ID of another configured exporter to dispatch to, e.g.
+	// "otlphttp" or "logging/debug". It must be declared in the collector's
+	// `exporters:` section like any other exporter.
+	Exporter string `mapstructure:"exporter"`
+
+	// Match is evaluated against the metric name for Metrics routes. It is
+	// ignored for Traces and Logs routes, which dispatch their whole batch
+	// to the first configured route.
+	Match string `mapstructure:"match"`
+}
+
+// Config defines the configuration for the split exporter: for each signal,
+// an ordered list of routes to other, already-configured exporters.
+type Config struct {
+	configmodels.ExporterSettings `mapstructure:",squash"`
+
+	// Traces is dispatched as a whole to its first configured route; further
+	// entries are currently unused.
+	Traces []RouteConfig `mapstructure:"traces"`
+
+	// Metrics are split by name: the first route whose Match accepts a given
+	// metric's name wins, falling back to the route with no Match, and
+	// finally to the first configured route if neither applies.
+	Metrics []RouteConfig `mapstructure:"metrics"`
+
+	// Logs is dispatched as a whole to its first configured route; further
+	// entries are currently unused.
+	Logs []RouteConfig `mapstructure:"logs"`
+}
+
+// Validate checks that every configured route names an exporter. Rejecting
+// a route that would create a cycle of split exporters routing into each
+// other requires the full exporter graph and is done when the routes are
+// resolved at exporter creation time; see buildExporter.
+func (cfg *Config) Validate() error {
+	for _, routes := range [][]RouteConfig{cfg.Traces, cfg.Metrics, cfg.Logs} {
+		for _, route := range routes {
+			if route.Exporter == "" {
+				return fmt.Errorf("route exporter id must not be empty")
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+// Copyright 2020 The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package splitexporter
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/consumer/pdata"
+)
+
+// resolvedRoute pairs a compiled match expression (nil matches everything)
+// with the exporter instance it dispatches to.
+type resolvedRoute struct {
+	exporterID string
+	match      *regexp.Regexp
+	exporter   component.Exporter
+}
+
+func newResolvedRoute(rc RouteConfig, exp component.Exporter) (*resolvedRoute, error) {
+	var re *regexp.Regexp
+	if rc.Match != "" {
+		var err error
+		re, err = regexp.Compile(rc.Match)
+		if err != nil {
+			return nil, fmt.Errorf("invalid match expression for route %q: %w", rc.Exporter, err)
+		}
+	}
+	return &resolvedRoute{exporterID: rc.Exporter, match: re, exporter: exp}, nil
+}
+
+// sharedExporter wraps one exporter instance shared across every split
+// exporter (and every route within each of them) that references the same
+// id for a given signal, so it is started and shut down exactly once no
+// matter how many owners it has.
+type sharedExporter struct {
+	id       string
+	exporter component.Exporter
+
+	mu      sync.Mutex
+	refs    int
+	started bool
+}
+
+// start registers one more owner and starts the underlying exporter the
+// first time any owner starts it.
+func (s *sharedExporter) start(ctx context.Context, host component.Host) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.refs++
+	if s.started {
+		return nil
+	}
+	if err := s.exporter.Start(ctx, host); err != nil {
+		s.refs--
+		return err
+	}
+	s.started = true
+	return nil
+}
+
+// shutdown releases one owner's reference, shutting down the underlying
+// exporter once the last owner has released it.
+func (s *sharedExporter) shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.refs > 0 {
+		s.refs--
+	}
+	if s.refs > 0 || !s.started {
+		return nil
+	}
+	s.started = false
+	return s.exporter.Shutdown(ctx)
+}
+
+// base owns the shared exporter instances a split exporter dispatches to.
+// Since the same sharedExporter may appear in more than one base, calling
+// Start on a base starts each underlying exporter only when its first owner
+// starts it, and calling Shutdown shuts it down only once all of its owners -
+// possibly several split exporters - have shut it down.
+type base struct {
+	instances []*sharedExporter
+}
+
+func (b *base) Start(ctx context.Context, host component.Host) error {
+	for _, se := range b.instances {
+		if err := se.start(ctx, host); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *base) Shutdown(ctx context.Context) error {
+	var errs []error
+	for _, se := range b.instances {
+		if err := se.shutdown(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return consumererror.Combine(errs)
+}
+
+type splitTracesExporter struct {
+	base
+	routes []*resolvedRoute
+}
+
+func newTracesExporter(routes []*resolvedRoute, instances []*sharedExporter) *splitTracesExporter {
+	return &splitTracesExporter{base: base{instances: instances}, routes: routes}
+}
+
+func (e *splitTracesExporter) ConsumeTraces(ctx context.Context, td pdata.Traces) error {
+	exp, ok := e.routes[0].exporter.(component.TracesExporter)
+	if !ok {
+		return fmt.Errorf("exporter %q does not support traces", e.routes[0].exporterID)
+	}
+	return exp.ConsumeTraces(ctx, td)
+}
+
+type splitLogsExporter struct {
+	base
+	routes []*resolvedRoute
+}
+
+func newLogsExporter(routes []*resolvedRoute, instances []*sharedExporter) *splitLogsExporter {
+	return &splitLogsExporter{base: base{instances: instances}, routes: routes}
+}
+
+func (e *splitLogsExporter) ConsumeLogs(ctx context.Context, ld pdata.Logs) error {
+	exp, ok := e.routes[0].exporter.(component.LogsExporter)
+	if !ok {
+		return fmt.Errorf("exporter %q does not support logs", e.routes[0].exporterID)
+	}
+	return exp.ConsumeLogs(ctx, ld)
+}
+
+type splitMetricsExporter struct {
+	base
+	routes []*resolvedRoute
+}
+
+func newMetricsExporter(routes []*resolvedRoute, instances []*sharedExporter) *splitMetricsExporter {
+	return &splitMetricsExporter{base: base{instances: instances}, routes: routes}
+}
+
+// routeForMetric returns the first route whose Match accepts name, falling
+// back to the route with no Match (the catch-all default), and finally to
+// the first configured route if neither applies.
+func (e *splitMetricsExporter) routeForMetric(name string) *resolvedRoute {
+	var fallback *resolvedRoute
+	for _, route := range e.routes {
+		if route.match == nil {
+			if fallback == nil {
+				fallback = route
+			}
+			continue
+		}
+		if route.match.MatchString(name) {
+			return route
+		}
+	}
+	if fallback != nil {
+		return fallback
+	}
+	return e.routes[0]
+}
+
+func (e *splitMetricsExporter) ConsumeMetrics(ctx context.Context, md pdata.Metrics) error {
+	batches := make(map[*resolvedRoute]pdata.Metrics)
+
+	rms := md.ResourceMetrics()
+	for i := 0; i < rms.Len(); i++ {
+		rm := rms.At(i)
+		destRMs := make(map[*resolvedRoute]pdata.ResourceMetrics)
+
+		ilms := rm.InstrumentationLibraryMetrics()
+		for j := 0; j < ilms.Len(); j++ {
+			ilm := ilms.At(j)
+			destILMs := make(map[*resolvedRoute]pdata.InstrumentationLibraryMetrics)
+
+			metrics := ilm.Metrics()
+			for k := 0; k < metrics.Len(); k++ {
+				metric := metrics.At(k)
+				route := e.routeForMetric(metric.Name())
+
+				destRM, ok := destRMs[route]
+				if !ok {
+					batch, ok := batches[route]
+					if !ok {
+						batch = pdata.NewMetrics()
+						batches[route] = batch
+					}
+					destRM = batch.ResourceMetrics().AppendEmpty()
+					rm.Resource().CopyTo(destRM.Resource())
+					destRMs[route] = destRM
+				}
+
+				destILM, ok := destILMs[route]
+				if !ok {
+					destILM = destRM.InstrumentationLibraryMetrics().AppendEmpty()
+					ilm.InstrumentationLibrary().CopyTo(destILM.InstrumentationLibrary())
+					destILMs[route] = destILM
+				}
+
+				metric.CopyTo(destILM.Metrics().AppendEmpty())
+			}
+		}
+	}
+
+	var errs []error
+	for route, batch := range batches {
+		exp, ok := route.exporter.(component.MetricsExporter)
+		if !ok {
+			errs = append(errs, fmt.Errorf("exporter %q does not support metrics", route.exporterID))
+			continue
+		}
+		if err := exp.ConsumeMetrics(ctx, batch); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return consumererror.Combine(errs)
+}
@@ -0,0 +1,194 @@
+// Copyright 2020 The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package splitexporter
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config/configmodels"
+	"go.opentelemetry.io/collector/consumer/pdata"
+)
+
+// fakeExporter implements component.TracesExporter, MetricsExporter, and
+// LogsExporter with call counters, so tests can assert on lifecycle and
+// dispatch without a real backend.
+type fakeExporter struct {
+	startCount    int32
+	shutdownCount int32
+	metricsCalls  []pdata.Metrics
+	tracesCalls   int32
+	logsCalls     int32
+}
+
+func (f *fakeExporter) Start(context.Context, component.Host) error {
+	atomic.AddInt32(&f.startCount, 1)
+	return nil
+}
+
+func (f *fakeExporter) Shutdown(context.Context) error {
+	atomic.AddInt32(&f.shutdownCount, 1)
+	return nil
+}
+
+func (f *fakeExporter) ConsumeTraces(context.Context, pdata.Traces) error {
+	atomic.AddInt32(&f.tracesCalls, 1)
+	return nil
+}
+
+func (f *fakeExporter) ConsumeLogs(context.Context, pdata.Logs) error {
+	atomic.AddInt32(&f.logsCalls, 1)
+	return nil
+}
+
+func (f *fakeExporter) ConsumeMetrics(_ context.Context, md pdata.Metrics) error {
+	f.metricsCalls = append(f.metricsCalls, md)
+	return nil
+}
+
+func metricNames(md pdata.Metrics) []string {
+	var names []string
+	rms := md.ResourceMetrics()
+	for i := 0; i < rms.Len(); i++ {
+		ilms := rms.At(i).InstrumentationLibraryMetrics()
+		for j := 0; j < ilms.Len(); j++ {
+			metrics := ilms.At(j).Metrics()
+			for k := 0; k < metrics.Len(); k++ {
+				names = append(names, metrics.At(k).Name())
+			}
+		}
+	}
+	return names
+}
+
+func buildTestMetrics(names ...string) pdata.Metrics {
+	md := pdata.NewMetrics()
+	rm := md.ResourceMetrics().AppendEmpty()
+	ilm := rm.InstrumentationLibraryMetrics().AppendEmpty()
+	for _, name := range names {
+		m := ilm.Metrics().AppendEmpty()
+		m.SetName(name)
+	}
+	return md
+}
+
+func TestBase_StartShutdownCalledOncePerInstance(t *testing.T) {
+	a := &sharedExporter{exporter: &fakeExporter{}}
+	b := &sharedExporter{exporter: &fakeExporter{}}
+
+	base := base{instances: []*sharedExporter{a, b}}
+	require.NoError(t, base.Start(context.Background(), nil))
+	require.NoError(t, base.Shutdown(context.Background()))
+
+	assert.EqualValues(t, 1, a.exporter.(*fakeExporter).startCount)
+	assert.EqualValues(t, 1, b.exporter.(*fakeExporter).startCount)
+	assert.EqualValues(t, 1, a.exporter.(*fakeExporter).shutdownCount)
+	assert.EqualValues(t, 1, b.exporter.(*fakeExporter).shutdownCount)
+}
+
+// TestBase_DedupedInstanceStartedOnce exercises buildRoutes/buildExporter
+// end-to-end for two independent split exporters that both route to the
+// same underlying exporter id: the fakeExporter backing that id must be
+// started and shut down exactly once, even though both split exporters'
+// bases own it and both have their Start/Shutdown called.
+func TestBase_DedupedInstanceStartedOnce(t *testing.T) {
+	shared := &fakeExporter{}
+	factory := &fakeFactory{typeVal: "otlphttp", exp: shared}
+	cfg := &fakeExporterConfig{configmodels.ExporterSettings{TypeVal: "otlphttp", NameVal: "otlphttp"}}
+
+	SetResolvers(
+		component.Factories{Exporters: map[configmodels.Type]component.ExporterFactory{"otlphttp": factory}},
+		configmodels.Exporters{"otlphttp": cfg},
+	)
+	t.Cleanup(func() { SetResolvers(component.Factories{}, nil) })
+
+	_, sharedA, err := buildRoutes(context.Background(), component.ExporterCreateParams{}, []RouteConfig{{Exporter: "otlphttp"}}, signalTraces)
+	require.NoError(t, err)
+	_, sharedB, err := buildRoutes(context.Background(), component.ExporterCreateParams{}, []RouteConfig{{Exporter: "otlphttp"}}, signalTraces)
+	require.NoError(t, err)
+
+	baseA := base{instances: sharedA}
+	baseB := base{instances: sharedB}
+
+	require.NoError(t, baseA.Start(context.Background(), nil))
+	require.NoError(t, baseB.Start(context.Background(), nil))
+	assert.EqualValues(t, 1, shared.startCount, "the underlying exporter must only be started once across both owners")
+
+	require.NoError(t, baseA.Shutdown(context.Background()))
+	assert.EqualValues(t, 0, shared.shutdownCount, "must not shut down while baseB still holds a reference")
+
+	require.NoError(t, baseB.Shutdown(context.Background()))
+	assert.EqualValues(t, 1, shared.shutdownCount, "the underlying exporter must only be shut down once the last owner releases it")
+}
+
+func TestSplitTracesExporter_DispatchesToFirstRoute(t *testing.T) {
+	exp := &fakeExporter{}
+	route, err := newResolvedRoute(RouteConfig{Exporter: "otlphttp"}, exp)
+	require.NoError(t, err)
+
+	e := newTracesExporter([]*resolvedRoute{route}, []*sharedExporter{{exporter: exp}})
+	require.NoError(t, e.ConsumeTraces(context.Background(), pdata.NewTraces()))
+	assert.EqualValues(t, 1, exp.tracesCalls)
+}
+
+func TestSplitLogsExporter_DispatchesToFirstRoute(t *testing.T) {
+	exp := &fakeExporter{}
+	route, err := newResolvedRoute(RouteConfig{Exporter: "logging"}, exp)
+	require.NoError(t, err)
+
+	e := newLogsExporter([]*resolvedRoute{route}, []*sharedExporter{{exporter: exp}})
+	require.NoError(t, e.ConsumeLogs(context.Background(), pdata.NewLogs()))
+	assert.EqualValues(t, 1, exp.logsCalls)
+}
+
+func TestSplitMetricsExporter_RouteForMetric(t *testing.T) {
+	expA := &fakeExporter{}
+	expB := &fakeExporter{}
+
+	routeA, err := newResolvedRoute(RouteConfig{Exporter: "a", Match: "^request_"}, expA)
+	require.NoError(t, err)
+	routeDefault, err := newResolvedRoute(RouteConfig{Exporter: "b"}, expB)
+	require.NoError(t, err)
+
+	e := newMetricsExporter([]*resolvedRoute{routeA, routeDefault}, []*sharedExporter{{exporter: expA}, {exporter: expB}})
+
+	assert.Same(t, routeA, e.routeForMetric("request_count"))
+	assert.Same(t, routeDefault, e.routeForMetric("queue_size"))
+}
+
+func TestSplitMetricsExporter_ConsumeMetricsSplitsByRoute(t *testing.T) {
+	expA := &fakeExporter{}
+	expB := &fakeExporter{}
+
+	routeA, err := newResolvedRoute(RouteConfig{Exporter: "a", Match: "^request_"}, expA)
+	require.NoError(t, err)
+	routeDefault, err := newResolvedRoute(RouteConfig{Exporter: "b"}, expB)
+	require.NoError(t, err)
+
+	e := newMetricsExporter([]*resolvedRoute{routeA, routeDefault}, []*sharedExporter{{exporter: expA}, {exporter: expB}})
+
+	md := buildTestMetrics("request_count", "request_latency", "queue_size")
+	require.NoError(t, e.ConsumeMetrics(context.Background(), md))
+
+	require.Len(t, expA.metricsCalls, 1)
+	require.Len(t, expB.metricsCalls, 1)
+	assert.ElementsMatch(t, []string{"request_count", "request_latency"}, metricNames(expA.metricsCalls[0]))
+	assert.ElementsMatch(t, []string{"queue_size"}, metricNames(expB.metricsCalls[0]))
+}
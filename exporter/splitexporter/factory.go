@@ -0,0 +1,111 @@
+// Copyright 2020 The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package splitexporter
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config/configmodels"
+)
+
+const typeStr = "split"
+
+// NewFactory returns a component.ExporterFactory for the split exporter.
+func NewFactory() component.ExporterFactory {
+	return component.NewExporterFactory(
+		typeStr,
+		createDefaultConfig,
+		component.WithTracesExporter(createTracesExporter),
+		component.WithMetricsExporter(createMetricsExporter),
+		component.WithLogsExporter(createLogsExporter),
+	)
+}
+
+func createDefaultConfig() configmodels.Exporter {
+	return &Config{
+		ExporterSettings: configmodels.ExporterSettings{
+			TypeVal: typeStr,
+			NameVal: typeStr,
+		},
+	}
+}
+
+func createTracesExporter(
+	ctx context.Context,
+	params component.ExporterCreateParams,
+	cfg configmodels.Exporter,
+) (component.TracesExporter, error) {
+	oCfg := cfg.(*Config)
+	if len(oCfg.Traces) == 0 {
+		return nil, fmt.Errorf("split exporter %q has no traces routes configured", oCfg.Name())
+	}
+
+	ctx, err := withBuilding(ctx, oCfg.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	routes, instances, err := buildRoutes(ctx, params, oCfg.Traces, signalTraces)
+	if err != nil {
+		return nil, err
+	}
+	return newTracesExporter(routes, instances), nil
+}
+
+func createMetricsExporter(
+	ctx context.Context,
+	params component.ExporterCreateParams,
+	cfg configmodels.Exporter,
+) (component.MetricsExporter, error) {
+	oCfg := cfg.(*Config)
+	if len(oCfg.Metrics) == 0 {
+		return nil, fmt.Errorf("split exporter %q has no metrics routes configured", oCfg.Name())
+	}
+
+	ctx, err := withBuilding(ctx, oCfg.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	routes, instances, err := buildRoutes(ctx, params, oCfg.Metrics, signalMetrics)
+	if err != nil {
+		return nil, err
+	}
+	return newMetricsExporter(routes, instances), nil
+}
+
+func createLogsExporter(
+	ctx context.Context,
+	params component.ExporterCreateParams,
+	cfg configmodels.Exporter,
+) (component.LogsExporter, error) {
+	oCfg := cfg.(*Config)
+	if len(oCfg.Logs) == 0 {
+		return nil, fmt.Errorf("split exporter %q has no logs routes configured", oCfg.Name())
+	}
+
+	ctx, err := withBuilding(ctx, oCfg.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	routes, instances, err := buildRoutes(ctx, params, oCfg.Logs, signalLogs)
+	if err != nil {
+		return nil, err
+	}
+	return newLogsExporter(routes, instances), nil
+}
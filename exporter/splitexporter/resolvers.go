@@ -0,0 +1,201 @@
+// Copyright 2020 The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package splitexporter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config/configmodels"
+)
+
+type signalKind int
+
+const (
+	signalTraces signalKind = iota
+	signalMetrics
+	signalLogs
+)
+
+// builtKey identifies one (exporter id, signal) combination in the shared
+// build cache below.
+type builtKey struct {
+	id     string
+	signal signalKind
+}
+
+// builtExporter memoizes the single shared instance built for a builtKey,
+// regardless of how many split exporters reference it.
+type builtExporter struct {
+	once   sync.Once
+	shared *sharedExporter
+	err    error
+}
+
+// resolvers is deliberately process-wide, not per-Collector: the
+// component.ExporterFactory functions split exporters are built through
+// (createTracesExporter and friends in factory.go) only receive a
+// context.Context and the split exporter's own config, with no per-instance
+// handle to thread through. Only one *service.Collector using splitexporter
+// may be running in a given process at a time - a second concurrent
+// Collector with a different configuration will stomp this one's
+// resolvers.factories/exporters and build cache. This is safe for the normal
+// one-collector-per-process deployment (a collector binary, or a single
+// embedding service) but not for tests or embedders that run multiple
+// Collectors concurrently in-process.
+var resolvers struct {
+	mu        sync.Mutex
+	factories component.Factories
+	exporters configmodels.Exporters
+	built     map[builtKey]*builtExporter
+}
+
+// SetResolvers gives the split exporter factory access to the full set of
+// component factories and configured exporters, so it can build its own
+// private instances of whatever exporters its routes reference. It must be
+// called before the collector builds its pipelines; service.Collector calls
+// it itself every time it loads a configuration.
+//
+// SetResolvers also drops the shared build cache: each new configuration
+// gets its own generation of exporter instances rather than reusing ones
+// built for a previous (and by now shut down) configuration.
+//
+// SetResolvers is process-wide state (see the resolvers var doc): do not
+// call it concurrently from more than one *service.Collector in the same
+// process.
+func SetResolvers(factories component.Factories, exporters configmodels.Exporters) {
+	resolvers.mu.Lock()
+	defer resolvers.mu.Unlock()
+	resolvers.factories = factories
+	resolvers.exporters = exporters
+	resolvers.built = nil
+}
+
+type buildingKey struct{}
+
+// withBuilding adds id to the set of exporters currently under construction
+// on ctx, returning an error if id is already in it - which means a split
+// exporter's routes eventually lead back to itself.
+func withBuilding(ctx context.Context, id string) (context.Context, error) {
+	building, _ := ctx.Value(buildingKey{}).(map[string]bool)
+	if building[id] {
+		return ctx, fmt.Errorf("cycle detected in split exporter routing: %q references itself transitively", id)
+	}
+
+	next := make(map[string]bool, len(building)+1)
+	for k := range building {
+		next[k] = true
+	}
+	next[id] = true
+	return context.WithValue(ctx, buildingKey{}, next), nil
+}
+
+// buildExporter constructs a fresh instance of the exporter configured under
+// id via its own factory, for a split exporter to own and forward to.
+func buildExporter(ctx context.Context, params component.ExporterCreateParams, id string, signal signalKind) (component.Exporter, error) {
+	resolvers.mu.Lock()
+	factories := resolvers.factories
+	exporters := resolvers.exporters
+	resolvers.mu.Unlock()
+
+	cfg, ok := exporters[id]
+	if !ok {
+		return nil, fmt.Errorf("split exporter route references undefined exporter %q", id)
+	}
+
+	factory, ok := factories.Exporters[cfg.Type()]
+	if !ok {
+		return nil, fmt.Errorf("no factory registered for exporter type %q referenced by route %q", cfg.Type(), id)
+	}
+
+	// id is not marked here: if cfg is itself a split exporter, its own
+	// createTracesExporter/createMetricsExporter/createLogsExporter marks
+	// id via withBuilding on entry. Marking it twice for the same id would
+	// make any legitimate, non-cyclic nesting (e.g. split/a -> split/b) look
+	// like a self-reference the moment split/b's factory runs.
+	switch signal {
+	case signalTraces:
+		return factory.CreateTracesExporter(ctx, params, cfg)
+	case signalMetrics:
+		return factory.CreateMetricsExporter(ctx, params, cfg)
+	case signalLogs:
+		return factory.CreateLogsExporter(ctx, params, cfg)
+	default:
+		return nil, fmt.Errorf("unsupported signal kind %d", signal)
+	}
+}
+
+// getOrBuildShared returns the sharedExporter for (id, signal), building it
+// through buildExporter at most once regardless of how many split exporters
+// - across the whole collector, not just within a single buildRoutes call -
+// end up referencing the same id for the same signal. That single shared
+// instance is what base.Start/base.Shutdown operate on, so it is started and
+// shut down exactly once no matter how many owners it has.
+func getOrBuildShared(ctx context.Context, params component.ExporterCreateParams, id string, signal signalKind) (*sharedExporter, error) {
+	key := builtKey{id: id, signal: signal}
+
+	resolvers.mu.Lock()
+	if resolvers.built == nil {
+		resolvers.built = make(map[builtKey]*builtExporter)
+	}
+	be, ok := resolvers.built[key]
+	if !ok {
+		be = &builtExporter{}
+		resolvers.built[key] = be
+	}
+	resolvers.mu.Unlock()
+
+	be.once.Do(func() {
+		exp, err := buildExporter(ctx, params, id, signal)
+		if err != nil {
+			be.err = err
+			return
+		}
+		be.shared = &sharedExporter{id: id, exporter: exp}
+	})
+	return be.shared, be.err
+}
+
+// buildRoutes resolves configs into routes against shared exporter
+// instances, referencing each distinct exporter at most once in the
+// returned slice even if several routes in configs name the same one.
+func buildRoutes(ctx context.Context, params component.ExporterCreateParams, configs []RouteConfig, signal signalKind) ([]*resolvedRoute, []*sharedExporter, error) {
+	built := make(map[string]*sharedExporter, len(configs))
+	var shared []*sharedExporter
+	var routes []*resolvedRoute
+
+	for _, rc := range configs {
+		se, ok := built[rc.Exporter]
+		if !ok {
+			var err error
+			se, err = getOrBuildShared(ctx, params, rc.Exporter, signal)
+			if err != nil {
+				return nil, nil, err
+			}
+			built[rc.Exporter] = se
+			shared = append(shared, se)
+		}
+
+		route, err := newResolvedRoute(rc, se.exporter)
+		if err != nil {
+			return nil, nil, err
+		}
+		routes = append(routes, route)
+	}
+
+	return routes, shared, nil
+}
@@ -0,0 +1,140 @@
+// Copyright 2020 The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package splitexporter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config/configmodels"
+)
+
+// fakeFactory is a minimal component.ExporterFactory that hands back a
+// pre-built fakeExporter for any signal, recording how many times each
+// Create* method is called.
+type fakeFactory struct {
+	typeVal      configmodels.Type
+	exp          *fakeExporter
+	tracesCalls  int
+	metricsCalls int
+	logsCalls    int
+}
+
+func (f *fakeFactory) Type() configmodels.Type                     { return f.typeVal }
+func (f *fakeFactory) CreateDefaultConfig() configmodels.Exporter { return nil }
+
+func (f *fakeFactory) CreateTracesExporter(context.Context, component.ExporterCreateParams, configmodels.Exporter) (component.TracesExporter, error) {
+	f.tracesCalls++
+	return f.exp, nil
+}
+
+func (f *fakeFactory) CreateMetricsExporter(context.Context, component.ExporterCreateParams, configmodels.Exporter) (component.MetricsExporter, error) {
+	f.metricsCalls++
+	return f.exp, nil
+}
+
+func (f *fakeFactory) CreateLogsExporter(context.Context, component.ExporterCreateParams, configmodels.Exporter) (component.LogsExporter, error) {
+	f.logsCalls++
+	return f.exp, nil
+}
+
+type fakeExporterConfig struct {
+	configmodels.ExporterSettings
+}
+
+func TestBuildRoutes_DedupesSameExporterAcrossRoutes(t *testing.T) {
+	factory := &fakeFactory{typeVal: "otlphttp", exp: &fakeExporter{}}
+	cfg := &fakeExporterConfig{configmodels.ExporterSettings{TypeVal: "otlphttp", NameVal: "otlphttp"}}
+
+	SetResolvers(
+		component.Factories{Exporters: map[configmodels.Type]component.ExporterFactory{"otlphttp": factory}},
+		configmodels.Exporters{"otlphttp": cfg},
+	)
+	t.Cleanup(func() { SetResolvers(component.Factories{}, nil) })
+
+	routes, instances, err := buildRoutes(context.Background(), component.ExporterCreateParams{}, []RouteConfig{
+		{Exporter: "otlphttp", Match: "^a"},
+		{Exporter: "otlphttp"},
+	}, signalMetrics)
+	require.NoError(t, err)
+
+	assert.Len(t, routes, 2)
+	assert.Len(t, instances, 1, "the same exporter id referenced twice must only be built once")
+	assert.Equal(t, 1, factory.metricsCalls)
+}
+
+func TestBuildExporter_UndefinedRouteExporter(t *testing.T) {
+	SetResolvers(component.Factories{Exporters: map[configmodels.Type]component.ExporterFactory{}}, configmodels.Exporters{})
+	t.Cleanup(func() { SetResolvers(component.Factories{}, nil) })
+
+	_, err := buildExporter(context.Background(), component.ExporterCreateParams{}, "missing", signalTraces)
+	assert.Error(t, err)
+}
+
+func TestWithBuilding_DetectsCycle(t *testing.T) {
+	ctx, err := withBuilding(context.Background(), "split/a")
+	require.NoError(t, err)
+
+	_, err = withBuilding(ctx, "split/a")
+	assert.Error(t, err)
+}
+
+func TestCreateTracesExporter_AllowsNonCyclicNesting(t *testing.T) {
+	leafFactory := &fakeFactory{typeVal: "otlphttp", exp: &fakeExporter{}}
+	leafCfg := &fakeExporterConfig{configmodels.ExporterSettings{TypeVal: "otlphttp", NameVal: "otlphttp"}}
+
+	splitB := &Config{
+		ExporterSettings: configmodels.ExporterSettings{TypeVal: typeStr, NameVal: "split/b"},
+		Traces:           []RouteConfig{{Exporter: "otlphttp"}},
+	}
+	splitA := &Config{
+		ExporterSettings: configmodels.ExporterSettings{TypeVal: typeStr, NameVal: "split/a"},
+		Traces:           []RouteConfig{{Exporter: "split/b"}},
+	}
+
+	SetResolvers(
+		component.Factories{Exporters: map[configmodels.Type]component.ExporterFactory{
+			typeStr:    NewFactory(),
+			"otlphttp": leafFactory,
+		}},
+		configmodels.Exporters{"split/a": splitA, "split/b": splitB, "otlphttp": leafCfg},
+	)
+	t.Cleanup(func() { SetResolvers(component.Factories{}, nil) })
+
+	// A legitimate split/a -> split/b -> otlphttp chain is not a cycle and
+	// must build successfully.
+	_, err := createTracesExporter(context.Background(), component.ExporterCreateParams{}, splitA)
+	require.NoError(t, err)
+}
+
+func TestCreateTracesExporter_DetectsSelfReferencingCycle(t *testing.T) {
+	splitCfg := &Config{
+		ExporterSettings: configmodels.ExporterSettings{TypeVal: typeStr, NameVal: "split/a"},
+		Traces:           []RouteConfig{{Exporter: "split/a"}},
+	}
+
+	SetResolvers(
+		component.Factories{Exporters: map[configmodels.Type]component.ExporterFactory{typeStr: NewFactory()}},
+		configmodels.Exporters{"split/a": splitCfg},
+	)
+	t.Cleanup(func() { SetResolvers(component.Factories{}, nil) })
+
+	_, err := createTracesExporter(context.Background(), component.ExporterCreateParams{}, splitCfg)
+	assert.Error(t, err)
+}
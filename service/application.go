@@ -25,6 +25,7 @@ import (
 	"os/signal"
 	"runtime"
 	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
@@ -35,9 +36,11 @@ import (
 	"go.opentelemetry.io/collector/config/configtelemetry"
 	"go.opentelemetry.io/collector/config/experimental/configsource"
 	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/exporter/splitexporter"
 	"go.opentelemetry.io/collector/internal/collector/telemetry"
 	"go.opentelemetry.io/collector/service/internal/builder"
 	"go.opentelemetry.io/collector/service/parserprovider"
+	"go.opentelemetry.io/collector/service/selftelemetry"
 )
 
 const (
@@ -46,16 +49,34 @@ const (
 	extensionzPath = "extensionz"
 )
 
+// defaultShutdownTimeout is the time allotted to drain the pipeline on
+// shutdown when Parameters.ShutdownTimeout is unset and --shutdown-timeout
+// is not passed.
+const defaultShutdownTimeout = 30 * time.Second
+
 // State defines Collector's state.
 type State int
 
 const (
 	Starting State = iota
 	Running
+	// Draining is entered once a shutdown has been requested: receivers stop
+	// accepting new data while processors and exporters flush in-flight
+	// batches, up to ShutdownTimeout.
+	Draining
 	Closing
 	Closed
 )
 
+var shutdownTimeoutFlag time.Duration
+
+// shutdownFlags registers the command-line flags that control the graceful
+// shutdown sequence.
+func shutdownFlags(flags *flag.FlagSet) {
+	flags.DurationVar(&shutdownTimeoutFlag, "shutdown-timeout", defaultShutdownTimeout,
+		"Maximum time to wait for the pipeline to drain in-flight data on shutdown before forcing it closed.")
+}
+
 // Collector represents a server providing the OpenTelemetry Collector service.
 type Collector struct {
 	info    component.BuildInfo
@@ -69,9 +90,21 @@ type Collector struct {
 
 	parserProvider parserprovider.ParserProvider
 
+	// selfTelemetry reports anonymous usage telemetry about this Collector,
+	// unless disabled.
+	selfTelemetry *selftelemetry.Reporter
+
+	// shutdownTimeout bounds how long the Draining state waits for the
+	// pipeline to flush in-flight data before components are force-closed.
+	shutdownTimeout time.Duration
+
 	// stopTestChan is used to terminate the collector server in end to end tests.
 	stopTestChan chan struct{}
 
+	// shutdownDone is closed once execute has finished the shutdown sequence,
+	// letting a caller of Shutdown know the collector has fully stopped.
+	shutdownDone chan struct{}
+
 	// signalsChannel is used to receive termination signals from the OS.
 	signalsChannel chan os.Signal
 
@@ -92,6 +125,10 @@ type Parameters struct {
 	ParserProvider parserprovider.ParserProvider
 	// LoggingOptions provides a way to change behavior of zap logging.
 	LoggingOptions []zap.Option
+	// ShutdownTimeout bounds how long Shutdown waits for the pipeline to
+	// drain in-flight data before forcing remaining components closed.
+	// Defaults to 30s; overridden by --shutdown-timeout if passed.
+	ShutdownTimeout time.Duration
 }
 
 // New creates and returns a new instance of Collector.
@@ -115,6 +152,14 @@ func New(params Parameters) (*Collector, error) {
 				return fmt.Errorf("failed to get logger: %w", err)
 			}
 
+			col.shutdownTimeout = params.ShutdownTimeout
+			if col.shutdownTimeout <= 0 {
+				col.shutdownTimeout = defaultShutdownTimeout
+			}
+			if cmd.Flags().Changed("shutdown-timeout") {
+				col.shutdownTimeout = shutdownTimeoutFlag
+			}
+
 			return col.execute(context.Background())
 		},
 	}
@@ -127,6 +172,8 @@ func New(params Parameters) (*Collector, error) {
 		telemetry.Flags,
 		builder.Flags,
 		loggerFlags,
+		selftelemetry.Flags,
+		shutdownFlags,
 	}
 	for _, addFlags := range addFlagsFns {
 		addFlags(flagSet)
@@ -136,8 +183,11 @@ func New(params Parameters) (*Collector, error) {
 
 	parserProvider := params.ParserProvider
 	if parserProvider == nil {
-		// use default provider.
+		// use default provider, wrapped to poll for hot reloads if requested.
 		parserProvider = parserprovider.Default()
+		if interval := parserprovider.PollInterval(); interval > 0 {
+			parserProvider = parserprovider.Polling(parserProvider, interval)
+		}
 	}
 	col.parserProvider = parserProvider
 
@@ -153,7 +203,9 @@ func (col *Collector) Run() error {
 	return col.rootCmd.Execute()
 }
 
-// GetStateChannel returns state channel of the collector server.
+// GetStateChannel returns state channel of the collector server. Consumers
+// should expect a Draining state between Running and Closing while the
+// collector waits for in-flight data to flush on shutdown.
 func (col *Collector) GetStateChannel() chan State {
 	return col.stateChannel
 }
@@ -169,16 +221,22 @@ func (col *Collector) GetLogger() *zap.Logger {
 	return col.logger
 }
 
-// Shutdown shuts down the collector server.
-func (col *Collector) Shutdown() {
-	// TODO: Implement a proper shutdown with graceful draining of the pipeline.
-	// See https://github.com/open-telemetry/opentelemetry-collector/issues/483.
+// Shutdown requests a graceful shutdown of the collector server: receivers
+// stop accepting new data while processors and exporters drain in-flight
+// batches, up to ShutdownTimeout. It returns once the collector has fully
+// stopped or ctx is done, whichever happens first.
+func (col *Collector) Shutdown(ctx context.Context) {
 	defer func() {
 		if r := recover(); r != nil {
 			col.logger.Info("stopTestChan already closed")
 		}
 	}()
 	close(col.stopTestChan)
+
+	select {
+	case <-col.shutdownDone:
+	case <-ctx.Done():
+	}
 }
 
 func (col *Collector) setupTelemetry(ballastSizeBytes uint64) error {
@@ -196,7 +254,8 @@ func (col *Collector) setupTelemetry(ballastSizeBytes uint64) error {
 func (col *Collector) runAndWaitForShutdownEvent() {
 	col.logger.Info("Everything is ready. Begin running and processing data.")
 
-	// plug SIGTERM signal into a channel.
+	// plug SIGTERM/SIGINT into a channel; a second one received while
+	// draining escalates to an immediate shutdown, see execute.
 	col.signalsChannel = make(chan os.Signal, 1)
 	signal.Notify(col.signalsChannel, os.Interrupt, syscall.SIGTERM)
 
@@ -211,7 +270,7 @@ func (col *Collector) runAndWaitForShutdownEvent() {
 	case <-col.stopTestChan:
 		col.logger.Info("Received stop test request")
 	}
-	col.stateChannel <- Closing
+	col.stateChannel <- Draining
 }
 
 // setupConfigurationComponents loads the config and starts the components. If all the steps succeeds it
@@ -233,6 +292,13 @@ func (col *Collector) setupConfigurationComponents(ctx context.Context) error {
 		return fmt.Errorf("invalid configuration: %w", err)
 	}
 
+	// Give the split exporter (if configured) access to the full set of
+	// factories and exporter configs so it can build its own private
+	// instances of whatever exporters its routes reference. This is
+	// process-wide state (see splitexporter's resolvers var doc): only one
+	// Collector using splitexporter may run per process at a time.
+	splitexporter.SetResolvers(col.factories, cfg.Exporters)
+
 	col.logger.Info("Applying configuration...")
 
 	service, err := newService(&settings{
@@ -253,32 +319,58 @@ func (col *Collector) setupConfigurationComponents(ctx context.Context) error {
 
 	col.service = service
 
-	// If provider is watchable start a goroutine watching for updates.
-	if watchable, ok := col.parserProvider.(parserprovider.Watchable); ok {
-		go func() {
-			err := watchable.WatchForUpdate()
-			switch {
-			// TODO: Move configsource.ErrSessionClosed to providerparser package to avoid depending on configsource.
-			case errors.Is(err, configsource.ErrSessionClosed):
-				// This is the case of shutdown of the whole collector server, nothing to do.
-				col.logger.Info("Config WatchForUpdate closed", zap.Error(err))
-				return
-			default:
-				col.logger.Warn("Config WatchForUpdated exited", zap.Error(err))
-				col.reloadService(context.Background())
-			}
-		}()
+	telemetryCfg, err := selftelemetry.ConfigFromParser(cp)
+	if err != nil {
+		col.logger.Warn("Failed to parse telemetry config, self-telemetry reporter will use defaults", zap.Error(err))
+	}
+	var statsSource selftelemetry.StatsSource
+	if s, ok := interface{}(applicationTelemetry).(selftelemetry.StatsSource); ok {
+		statsSource = s
 	}
+	col.selfTelemetry = selftelemetry.New(telemetryCfg, col.info, col.factories, statsSource, "", col.logger)
+	if err := col.selfTelemetry.Start(ctx); err != nil {
+		col.logger.Warn("Failed to start self-telemetry reporter", zap.Error(err))
+	}
+
+	col.startConfigWatch()
 
 	return nil
 }
 
+// startConfigWatch starts a goroutine watching col.parserProvider for
+// updates, if it implements Watchable. The goroutine exits as soon as
+// WatchForUpdate returns, whatever the outcome of the reload it triggers, so
+// every caller that can observe it returning - the initial call below, and
+// every exit path of reloadService - must re-arm it or hot-reload silently
+// stops working after the first change, successful or not.
+func (col *Collector) startConfigWatch() {
+	watchable, ok := col.parserProvider.(parserprovider.Watchable)
+	if !ok {
+		return
+	}
+
+	go func() {
+		err := watchable.WatchForUpdate()
+		switch {
+		// TODO: Move configsource.ErrSessionClosed to providerparser package to avoid depending on configsource.
+		case errors.Is(err, configsource.ErrSessionClosed):
+			// This is the case of shutdown of the whole collector server, nothing to do.
+			col.logger.Info("Config WatchForUpdate closed", zap.Error(err))
+			return
+		default:
+			col.logger.Warn("Config WatchForUpdated exited", zap.Error(err))
+			col.reloadService(context.Background())
+		}
+	}()
+}
+
 func (col *Collector) execute(ctx context.Context) error {
 	col.logger.Info("Starting "+col.info.Command+"...",
 		zap.String("Version", col.info.Version),
 		zap.Int("NumCPU", runtime.NumCPU()),
 	)
 	col.stateChannel <- Starting
+	col.shutdownDone = make(chan struct{})
 
 	// Set memory ballast
 	ballast, ballastSizeBytes := col.createMemoryBallast()
@@ -299,33 +391,82 @@ func (col *Collector) execute(ctx context.Context) error {
 	// Everything is ready, now run until an event requiring shutdown happens.
 	col.runAndWaitForShutdownEvent()
 
-	// Accumulate errors and proceed with shutting down remaining components.
-	var errs []error
+	return col.gracefulShutdown(ctx, ballast)
+}
 
-	// Begin shutdown sequence.
+// gracefulShutdown drains the pipeline and tears down the remaining
+// components, giving shutdownComponents - which shuts down the running
+// service, and with it its receivers, processors and exporters - up to
+// col.shutdownTimeout to flush in-flight batches. A second interrupt
+// escalates to an immediate forced shutdown, same as the timeout does.
+func (col *Collector) gracefulShutdown(ctx context.Context, ballast []byte) error {
 	runtime.KeepAlive(ballast)
-	col.logger.Info("Starting shutdown...")
+	col.logger.Info("Starting graceful shutdown...", zap.Duration("timeout", col.shutdownTimeout))
+
+	drainCtx, cancel := context.WithTimeout(ctx, col.shutdownTimeout)
+	defer cancel()
+
+	escalate := make(chan os.Signal, 1)
+	signal.Notify(escalate, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(escalate)
+
+	shutdownDone := make(chan error, 1)
+	go func() { shutdownDone <- col.shutdownComponents(drainCtx) }()
+
+	var err error
+	select {
+	case err = <-shutdownDone:
+	case <-escalate:
+		col.logger.Warn("Received second interrupt, forcing immediate shutdown")
+		cancel()
+		err = <-shutdownDone
+	case <-drainCtx.Done():
+		col.logger.Warn("Shutdown timeout exceeded, forcing remaining components closed")
+		err = <-shutdownDone
+	}
+
+	// Draining (sent in runAndWaitForShutdownEvent) spans the call to
+	// shutdownComponents above: only once the flush has actually finished
+	// (or been forced) do we report Closing.
+	col.stateChannel <- Closing
+	col.logger.Info("Shutdown complete.")
+	col.stateChannel <- Closed
+	close(col.stateChannel)
+	close(col.shutdownDone)
+
+	return err
+}
+
+// shutdownComponents tears down the config provider, the running service,
+// the self-telemetry reporter, and application telemetry, in that order,
+// logging progress as each one completes. ctx bounds how long the service
+// shutdown (which drains processors/exporters) is allowed to take; once it
+// is done the remaining components are force-closed regardless.
+func (col *Collector) shutdownComponents(ctx context.Context) error {
+	var errs []error
 
 	if closable, ok := col.parserProvider.(parserprovider.Closeable); ok {
+		col.logger.Info("Closing configuration provider...")
 		if err := closable.Close(ctx); err != nil {
 			errs = append(errs, fmt.Errorf("failed to close config: %w", err))
 		}
 	}
 
 	if col.service != nil {
+		col.logger.Info("Shutting down pipeline...")
 		if err := col.service.Shutdown(ctx); err != nil {
 			errs = append(errs, fmt.Errorf("failed to shutdown service: %w", err))
 		}
 	}
 
+	col.logger.Info("Stopping self-telemetry reporter...")
+	col.selfTelemetry.Stop()
+
+	col.logger.Info("Shutting down application telemetry...")
 	if err := applicationTelemetry.shutdown(); err != nil {
 		errs = append(errs, fmt.Errorf("failed to shutdown application telemetry: %w", err))
 	}
 
-	col.logger.Info("Shutdown complete.")
-	col.stateChannel <- Closed
-	close(col.stateChannel)
-
 	return consumererror.Combine(errs)
 }
 
@@ -340,12 +481,35 @@ func (col *Collector) createMemoryBallast() ([]byte, uint64) {
 	return nil, 0
 }
 
-// reloadService shutdowns the current col.service and setups a new one according
-// to the latest configuration. It requires that col.parserProvider and col.factories
-// are properly populated to finish successfully.
+// reloadService validates the latest configuration, then shuts down the
+// current col.service and sets up a new one according to it. It requires
+// that col.parserProvider and col.factories are properly populated to finish
+// successfully. If the latest configuration cannot be loaded or fails
+// Validate, the currently-running service is left untouched.
 func (col *Collector) reloadService(ctx context.Context) error {
+	cp, err := col.parserProvider.Get()
+	if err != nil {
+		col.logger.Warn("Failed to reload configuration, keeping current service running", zap.Error(err))
+		col.startConfigWatch()
+		return nil
+	}
+
+	cfg, err := configloader.Load(cp, col.factories)
+	if err != nil {
+		col.logger.Warn("Failed to reload configuration, keeping current service running", zap.Error(err))
+		col.startConfigWatch()
+		return nil
+	}
+
+	if err := cfg.Validate(); err != nil {
+		col.logger.Warn("New configuration is invalid, keeping current service running", zap.Error(err))
+		col.startConfigWatch()
+		return nil
+	}
+
 	if closeable, ok := col.parserProvider.(parserprovider.Closeable); ok {
 		if err := closeable.Close(ctx); err != nil {
+			col.startConfigWatch()
 			return fmt.Errorf("failed close current config provider: %w", err)
 		}
 	}
@@ -354,11 +518,15 @@ func (col *Collector) reloadService(ctx context.Context) error {
 		retiringService := col.service
 		col.service = nil
 		if err := retiringService.Shutdown(ctx); err != nil {
+			col.startConfigWatch()
 			return fmt.Errorf("failed to shutdown the retiring config: %w", err)
 		}
 	}
 
+	col.selfTelemetry.Stop()
+
 	if err := col.setupConfigurationComponents(ctx); err != nil {
+		col.startConfigWatch()
 		return fmt.Errorf("failed to setup configuration components: %w", err)
 	}
 
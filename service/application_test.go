@@ -0,0 +1,160 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config"
+	"go.opentelemetry.io/collector/config/experimental/configsource"
+)
+
+// fixedContentParserProvider returns a config.Parser built from whatever
+// YAML is currently stored in content.
+type fixedContentParserProvider struct {
+	content string
+}
+
+func (f *fixedContentParserProvider) Get() (*config.Parser, error) {
+	return config.NewParserFromBuffer(strings.NewReader(f.content))
+}
+
+// TestReloadService_InvalidConfigKeepsOldServiceRunning covers the third
+// reload scenario: when the latest configuration cannot be turned into a
+// valid Config (here, a receiver type that isn't registered in col.factories
+// causes configloader.Load to fail), reloadService must return without
+// error and without ever reaching the code that tears down col.service, so
+// the currently-running service is left untouched.
+func TestReloadService_InvalidConfigKeepsOldServiceRunning(t *testing.T) {
+	pp := &fixedContentParserProvider{content: "receivers:\n  nonexistent:\nservice:\n  pipelines:\n    traces:\n      receivers: [nonexistent]\n      exporters: []\n"}
+	oldService := &service{}
+	col := &Collector{
+		logger:         zap.NewNop(),
+		parserProvider: pp,
+		factories:      component.Factories{},
+		service:        oldService,
+	}
+
+	err := col.reloadService(context.Background())
+	assert.NoError(t, err, "an invalid new configuration must not be surfaced as a reload error")
+	assert.Same(t, oldService, col.service, "reloadService must not touch col.service when the new configuration is rejected")
+}
+
+// watchCountingParserProvider is Watchable on top of a
+// fixedContentParserProvider, signalling on watchCalled every time
+// WatchForUpdate is invoked and immediately reporting itself closed so the
+// goroutine started for it doesn't recurse back into reloadService.
+type watchCountingParserProvider struct {
+	fixedContentParserProvider
+	watchCalled chan struct{}
+}
+
+func (p *watchCountingParserProvider) WatchForUpdate() error {
+	select {
+	case p.watchCalled <- struct{}{}:
+	default:
+	}
+	return configsource.ErrSessionClosed
+}
+
+// TestReloadService_RearmsWatchOnInvalidConfig guards against the bug where
+// the goroutine that called WatchForUpdate and landed in reloadService
+// always exits once reloadService returns: unless every one of its exit
+// paths - including rejecting an invalid new configuration - re-arms the
+// watch, hot-reload works once and then silently stops for the rest of the
+// process's life.
+func TestReloadService_RearmsWatchOnInvalidConfig(t *testing.T) {
+	pp := &watchCountingParserProvider{
+		fixedContentParserProvider: fixedContentParserProvider{
+			content: "receivers:\n  nonexistent:\nservice:\n  pipelines:\n    traces:\n      receivers: [nonexistent]\n      exporters: []\n",
+		},
+		watchCalled: make(chan struct{}, 1),
+	}
+	col := &Collector{
+		logger:         zap.NewNop(),
+		parserProvider: pp,
+		factories:      component.Factories{},
+	}
+
+	err := col.reloadService(context.Background())
+	require.NoError(t, err)
+
+	select {
+	case <-pp.watchCalled:
+	case <-time.After(time.Second):
+		t.Fatal("expected reloadService to re-arm the config watch after rejecting an invalid configuration")
+	}
+}
+
+// flushingParserProvider stands in for a config provider fronting a real
+// pipeline: Close blocks for a bit, as a pipeline's Shutdown would while it
+// flushes batches queued just before shutdown, then records that the flush
+// completed.
+type flushingParserProvider struct {
+	flushed int32
+}
+
+func (p *flushingParserProvider) Get() (*config.Parser, error) {
+	return nil, nil
+}
+
+func (p *flushingParserProvider) Close(ctx context.Context) error {
+	time.Sleep(20 * time.Millisecond)
+	atomic.StoreInt32(&p.flushed, 1)
+	return nil
+}
+
+// TestGracefulShutdown_DrainsBeforeClosing guards against the Closing state
+// (and, transitively, whatever a consumer of GetStateChannel does in
+// response to it) being observed before in-flight data has actually been
+// flushed: Closing must only be sent once shutdownComponents - which is
+// what drains batches queued just before shutdown - has returned.
+func TestGracefulShutdown_DrainsBeforeClosing(t *testing.T) {
+	pp := &flushingParserProvider{}
+	col := &Collector{
+		logger:          zap.NewNop(),
+		parserProvider:  pp,
+		stateChannel:    make(chan State, Closed+1),
+		shutdownDone:    make(chan struct{}),
+		shutdownTimeout: time.Second,
+	}
+
+	var flushedWhenClosingObserved int32
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for s := range col.stateChannel {
+			if s == Closing {
+				flushedWhenClosingObserved = atomic.LoadInt32(&pp.flushed)
+			}
+		}
+	}()
+
+	err := col.gracefulShutdown(context.Background(), nil)
+	require.NoError(t, err)
+	<-done
+
+	assert.EqualValues(t, 1, flushedWhenClosingObserved,
+		"Closing must only be observable once shutdownComponents has finished flushing in-flight data")
+}
@@ -0,0 +1,91 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package parserprovider implements the ParserProvider used by the Collector
+// to load its own configuration, and optionally watch it for changes.
+package parserprovider
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/collector/config"
+)
+
+var (
+	configFlag       string
+	pollIntervalFlag time.Duration
+)
+
+// Flags registers the command-line flags for the default ParserProvider.
+func Flags(flags *flag.FlagSet) {
+	flags.StringVar(&configFlag, "config", "", "Path to the collector config file.")
+	flags.DurationVar(&pollIntervalFlag, "config-poll-interval", 0,
+		"If non-zero, re-read the config file on this interval and hot-reload the collector when its content changes. 0 disables polling.")
+}
+
+// PollInterval returns the value of --config-poll-interval. 0 means polling
+// is disabled.
+func PollInterval() time.Duration {
+	return pollIntervalFlag
+}
+
+// ParserProvider is the interface responsible for providing the
+// configuration's Parser.
+type ParserProvider interface {
+	// Get returns the config.Parser for the collector's configuration.
+	Get() (*config.Parser, error)
+}
+
+// Watchable is implemented by a ParserProvider that can notify the caller of
+// out-of-band configuration changes. Callers must type-assert for it since
+// not every provider supports it.
+type Watchable interface {
+	// WatchForUpdate blocks until either a configuration change is detected,
+	// in which case it returns nil, or the provider is closed, in which case
+	// it returns configsource.ErrSessionClosed.
+	WatchForUpdate() error
+}
+
+// Closeable is implemented by a ParserProvider that holds resources (open
+// files, background goroutines, network sessions) that must be released on
+// shutdown.
+type Closeable interface {
+	// Close releases any resources held by the provider.
+	Close(ctx context.Context) error
+}
+
+// Default returns the default ParserProvider, which loads the configuration
+// from the file given by the --config flag.
+func Default() ParserProvider {
+	return &fileParserProvider{fileName: configFlag}
+}
+
+type fileParserProvider struct {
+	fileName string
+}
+
+func (fpp *fileParserProvider) Get() (*config.Parser, error) {
+	if fpp.fileName == "" {
+		return nil, fmt.Errorf("config file not provided, use --config")
+	}
+
+	cp, err := config.NewParserFromFile(fpp.fileName)
+	if err != nil {
+		return nil, fmt.Errorf("error loading config file %q: %w", fpp.fileName, err)
+	}
+	return cp, nil
+}
@@ -0,0 +1,137 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parserprovider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/config"
+	"go.opentelemetry.io/collector/config/experimental/configsource"
+)
+
+// Polling wraps inner so that it implements Watchable by re-fetching the
+// configuration through inner.Get() on a ticker and comparing a hash of its
+// content to the previously observed one. Use it to add hot-reload support
+// to a ParserProvider with no native change notification, such as a plain
+// file, an HTTP endpoint, or an S3 object.
+func Polling(inner ParserProvider, interval time.Duration) ParserProvider {
+	return &pollingParserProvider{
+		inner:    inner,
+		interval: interval,
+	}
+}
+
+type pollingParserProvider struct {
+	inner    ParserProvider
+	interval time.Duration
+
+	// mu guards doneChan/doneClosed, which are reset at the start of every
+	// WatchForUpdate call. A config reload calls Close to end the current
+	// watch session and then immediately starts a new one on this same
+	// instance (the service re-applies whatever Get() now returns), so
+	// Close must stop only the current session rather than permanently
+	// killing the provider - otherwise hot-reload would fire once and
+	// never again.
+	mu         sync.Mutex
+	doneChan   chan struct{}
+	doneClosed bool
+}
+
+func (p *pollingParserProvider) Get() (*config.Parser, error) {
+	return p.inner.Get()
+}
+
+// WatchForUpdate polls inner.Get() every p.interval, returning nil as soon as
+// the hash of its content changes, or configsource.ErrSessionClosed once
+// Close has been called for this watch session. Each call starts a new
+// session, so WatchForUpdate may be called again after a previous call
+// returned to keep watching across config reloads.
+func (p *pollingParserProvider) WatchForUpdate() error {
+	done := p.startSession()
+
+	lastHash, err := p.currentHash()
+	// Nothing sane to compare against yet if this fails; the first
+	// successful fetch on a later tick becomes the baseline rather than
+	// failing the watch outright.
+	haveHash := err == nil
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			hash, err := p.currentHash()
+			if err != nil {
+				continue
+			}
+			if !haveHash {
+				lastHash, haveHash = hash, true
+				continue
+			}
+			if hash == lastHash {
+				continue
+			}
+			return nil
+		case <-done:
+			return configsource.ErrSessionClosed
+		}
+	}
+}
+
+// startSession (re)arms the done channel for a new watch session and
+// returns it.
+func (p *pollingParserProvider) startSession() chan struct{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.doneChan = make(chan struct{})
+	p.doneClosed = false
+	return p.doneChan
+}
+
+// Close ends the current watch session and, if inner is itself Closeable,
+// closes it. It is safe to call repeatedly, including across reloads that
+// start a new watch session afterwards.
+func (p *pollingParserProvider) Close(ctx context.Context) error {
+	p.mu.Lock()
+	if p.doneChan != nil && !p.doneClosed {
+		close(p.doneChan)
+		p.doneClosed = true
+	}
+	p.mu.Unlock()
+
+	if closeable, ok := p.inner.(Closeable); ok {
+		return closeable.Close(ctx)
+	}
+	return nil
+}
+
+func (p *pollingParserProvider) currentHash() ([sha256.Size]byte, error) {
+	cp, err := p.inner.Get()
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+
+	b, err := json.Marshal(cp.Viper().AllSettings())
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	return sha256.Sum256(b), nil
+}
@@ -0,0 +1,142 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parserprovider
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.opentelemetry.io/collector/config"
+	"go.opentelemetry.io/collector/config/experimental/configsource"
+)
+
+// fixedContentParserProvider returns a config.Parser built from the
+// "foo: <value>" YAML currently stored in value, letting tests mutate the
+// content the next Get() call will observe.
+type fixedContentParserProvider struct {
+	value atomic.Value // string
+	fails atomic.Value // bool
+}
+
+func newFixedContentParserProvider(initial string) *fixedContentParserProvider {
+	p := &fixedContentParserProvider{}
+	p.value.Store(initial)
+	p.fails.Store(false)
+	return p
+}
+
+func (f *fixedContentParserProvider) set(value string) {
+	f.value.Store(value)
+}
+
+func (f *fixedContentParserProvider) setFails(fails bool) {
+	f.fails.Store(fails)
+}
+
+func (f *fixedContentParserProvider) Get() (*config.Parser, error) {
+	if f.fails.Load().(bool) {
+		return nil, errors.New("simulated fetch failure")
+	}
+	return config.NewParserFromBuffer(strings.NewReader(f.value.Load().(string)))
+}
+
+func TestPollingParserProvider_NoChangeDoesNotReturn(t *testing.T) {
+	inner := newFixedContentParserProvider("foo: bar")
+	p := Polling(inner, 10*time.Millisecond)
+
+	done := make(chan error, 1)
+	go func() { done <- p.(Watchable).WatchForUpdate() }()
+
+	select {
+	case err := <-done:
+		t.Fatalf("expected WatchForUpdate to keep blocking on unchanged content, got %v", err)
+	case <-time.After(60 * time.Millisecond):
+	}
+
+	require.NoError(t, p.(Closeable).Close(nil))
+	assert.ErrorIs(t, <-done, configsource.ErrSessionClosed)
+}
+
+func TestPollingParserProvider_ChangeTriggersUpdate(t *testing.T) {
+	inner := newFixedContentParserProvider("foo: bar")
+	p := Polling(inner, 5*time.Millisecond)
+
+	done := make(chan error, 1)
+	go func() { done <- p.(Watchable).WatchForUpdate() }()
+
+	time.Sleep(20 * time.Millisecond)
+	inner.set("foo: baz")
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("expected WatchForUpdate to return after content changed")
+	}
+}
+
+func TestPollingParserProvider_WatchResumesAfterClose(t *testing.T) {
+	inner := newFixedContentParserProvider("foo: bar")
+	p := Polling(inner, 5*time.Millisecond)
+
+	done := make(chan error, 1)
+	go func() { done <- p.(Watchable).WatchForUpdate() }()
+	time.Sleep(20 * time.Millisecond)
+
+	// Simulate a config reload: the watch session is closed and then a new
+	// one is started on the same provider instance, as
+	// Collector.setupConfigurationComponents does after Collector.reloadService.
+	require.NoError(t, p.(Closeable).Close(nil))
+	assert.ErrorIs(t, <-done, configsource.ErrSessionClosed)
+
+	done = make(chan error, 1)
+	go func() { done <- p.(Watchable).WatchForUpdate() }()
+	time.Sleep(20 * time.Millisecond)
+
+	inner.set("foo: baz")
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("expected the new watch session to still detect config changes after a prior Close")
+	}
+}
+
+func TestPollingParserProvider_FetchFailureIsIgnored(t *testing.T) {
+	inner := newFixedContentParserProvider("foo: bar")
+	p := Polling(inner, 5*time.Millisecond)
+
+	done := make(chan error, 1)
+	go func() { done <- p.(Watchable).WatchForUpdate() }()
+
+	inner.setFails(true)
+	time.Sleep(20 * time.Millisecond)
+	inner.setFails(false)
+
+	select {
+	case err := <-done:
+		t.Fatalf("expected transient fetch failures not to trigger an update, got %v", err)
+	case <-time.After(30 * time.Millisecond):
+	}
+
+	require.NoError(t, p.(Closeable).Close(nil))
+	assert.ErrorIs(t, <-done, configsource.ErrSessionClosed)
+}
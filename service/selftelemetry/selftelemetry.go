@@ -0,0 +1,333 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package selftelemetry implements an opt-in/opt-out reporter that
+// periodically phones home a small, anonymous usage document describing the
+// running Collector. It is off by default for dev builds and can be turned
+// off for any build through a CLI flag, two environment variables, or the
+// collector's own configuration file.
+package selftelemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config"
+)
+
+const (
+	defaultInterval = 24 * time.Hour
+	defaultEndpoint = "https://otelcol-telemetry.opentelemetry.io/v1/report"
+	installIDFile   = "otelcol-install-id"
+
+	// minBackoff is the delay used after the first consecutive send
+	// failure; it doubles with every further failure up to cfg.Interval.
+	minBackoff = time.Second
+)
+
+var disableFlag bool
+
+// Flags registers the command-line flags specific to the self-telemetry
+// reporter.
+func Flags(flags *flag.FlagSet) {
+	flags.BoolVar(&disableFlag, "telemetry.disable", false, "Disable the collector's opt-out self-telemetry reporter.")
+}
+
+// Config is the `telemetry:` section of the collector's own configuration
+// file. All fields are optional.
+type Config struct {
+	// Disabled, if true, turns off the self-telemetry reporter regardless of
+	// any other setting.
+	Disabled bool `mapstructure:"disable"`
+
+	// Endpoint is where the periodic report is POSTed. Defaults to the
+	// project-operated collection endpoint.
+	Endpoint string `mapstructure:"endpoint"`
+
+	// Interval is how often a report is sent. Defaults to 24h.
+	Interval time.Duration `mapstructure:"interval"`
+}
+
+// ConfigFromParser extracts the optional `telemetry:` section from the root
+// configuration file. It returns the zero Config, and no error, when the
+// section is absent.
+func ConfigFromParser(v *config.Parser) (Config, error) {
+	var cfg Config
+	if v == nil || !v.IsSet("telemetry") {
+		return cfg, nil
+	}
+
+	sub, err := v.Sub("telemetry")
+	if err != nil {
+		return cfg, fmt.Errorf("failed to extract telemetry config: %w", err)
+	}
+	if err := sub.UnmarshalExact(&cfg); err != nil {
+		return cfg, fmt.Errorf("failed to unmarshal telemetry config: %w", err)
+	}
+	return cfg, nil
+}
+
+// Report is the JSON document POSTed to Config.Endpoint on every interval.
+type Report struct {
+	InstallID     string              `json:"install_id"`
+	BinaryInfo    component.BinaryInfo `json:"binary_info"`
+	OS            string              `json:"os"`
+	Arch          string              `json:"arch"`
+	UptimeSeconds float64             `json:"uptime_seconds"`
+	Receivers     []string            `json:"receivers"`
+	Processors    []string            `json:"processors"`
+	Exporters     []string            `json:"exporters"`
+	Extensions    []string            `json:"extensions"`
+	Successes     uint64              `json:"successes"`
+	Failures      uint64              `json:"failures"`
+}
+
+// StatsSource supplies the success/failure counters that go into a Report.
+// It is implemented optionally; a Reporter with no StatsSource reports both
+// counters as 0.
+type StatsSource interface {
+	Successes() uint64
+	Failures() uint64
+}
+
+// Reporter periodically sends anonymous usage telemetry about the running
+// Collector, unless disabled through one of the supported opt-out
+// mechanisms.
+type Reporter struct {
+	cfg       Config
+	info      component.BinaryInfo
+	factories component.Factories
+	stats     StatsSource
+	logger    *zap.Logger
+	client    *http.Client
+	dataDir   string
+	startTime time.Time
+
+	installID string
+	done      chan struct{}
+}
+
+// New creates a Reporter, or returns nil if self-telemetry is disabled. All
+// methods on Reporter are safe to call on a nil receiver so callers can use
+// the result unconditionally.
+func New(cfg Config, info component.BinaryInfo, factories component.Factories, stats StatsSource, dataDir string, logger *zap.Logger) *Reporter {
+	if disabled(cfg, info) {
+		logger.Info("Self-telemetry reporter is disabled")
+		return nil
+	}
+
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultInterval
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = defaultEndpoint
+	}
+	if dataDir == "" {
+		dataDir = DefaultDataDir()
+	}
+
+	return &Reporter{
+		cfg:       cfg,
+		info:      info,
+		factories: factories,
+		stats:     stats,
+		logger:    logger,
+		client:    &http.Client{Timeout: 30 * time.Second},
+		dataDir:   dataDir,
+		startTime: time.Now(),
+	}
+}
+
+// DefaultDataDir returns the directory the install UUID is persisted under
+// when the caller does not provide one.
+func DefaultDataDir() string {
+	if dir, err := os.UserConfigDir(); err == nil {
+		return filepath.Join(dir, "otelcol")
+	}
+	return filepath.Join(os.TempDir(), "otelcol")
+}
+
+// disabled evaluates, in order, the four supported opt-out mechanisms plus
+// the dev-build heuristic.
+func disabled(cfg Config, info component.BinaryInfo) bool {
+	switch {
+	case disableFlag:
+		return true
+	case os.Getenv("DO_NOT_TRACK") != "":
+		return true
+	case os.Getenv("OTELCOL_TELEMETRY") == "0":
+		return true
+	case cfg.Disabled:
+		return true
+	case info.Version == "latest" || strings.Contains(info.Version, "-dev"):
+		return true
+	default:
+		return false
+	}
+}
+
+// Start loads or creates the install UUID and begins the periodic reporting
+// loop. The loop runs until ctx is canceled or Stop is called.
+func (r *Reporter) Start(ctx context.Context) error {
+	if r == nil {
+		return nil
+	}
+
+	id, err := r.loadOrCreateInstallID()
+	if err != nil {
+		return fmt.Errorf("failed to initialize self-telemetry install id: %w", err)
+	}
+	r.installID = id
+	r.done = make(chan struct{})
+
+	go r.reportLoop(ctx)
+	return nil
+}
+
+// Stop terminates the periodic reporting loop.
+func (r *Reporter) Stop() {
+	if r == nil || r.done == nil {
+		return
+	}
+	close(r.done)
+}
+
+func (r *Reporter) reportLoop(ctx context.Context) {
+	timer := time.NewTimer(r.cfg.Interval)
+	defer timer.Stop()
+
+	var failures int
+	for {
+		select {
+		case <-timer.C:
+			if err := r.send(ctx); err != nil {
+				r.logger.Debug("Failed to send self-telemetry report", zap.Error(err))
+				failures++
+				timer.Reset(r.backoff(failures))
+				continue
+			}
+			failures = 0
+			timer.Reset(r.cfg.Interval)
+		case <-r.done:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// backoff returns the delay to wait before the next send attempt after
+// consecutiveFailures in a row, doubling from minBackoff and capped at the
+// configured report interval so a struggling endpoint is retried sooner
+// than the next full interval but never more aggressively than normal.
+func (r *Reporter) backoff(consecutiveFailures int) time.Duration {
+	delay := minBackoff << uint(consecutiveFailures-1)
+	if delay <= 0 || delay > r.cfg.Interval {
+		return r.cfg.Interval
+	}
+	return delay
+}
+
+func (r *Reporter) send(ctx context.Context) error {
+	body, err := json.Marshal(r.buildReport())
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("self-telemetry endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (r *Reporter) buildReport() Report {
+	report := Report{
+		InstallID:     r.installID,
+		BinaryInfo:    r.info,
+		OS:            runtime.GOOS,
+		Arch:          runtime.GOARCH,
+		UptimeSeconds: time.Since(r.startTime).Seconds(),
+		Receivers:     factoryKinds(r.factories.Receivers),
+		Processors:    factoryKinds(r.factories.Processors),
+		Exporters:     factoryKinds(r.factories.Exporters),
+		Extensions:    factoryKinds(r.factories.Extensions),
+	}
+
+	if r.stats != nil {
+		report.Successes = r.stats.Successes()
+		report.Failures = r.stats.Failures()
+	}
+
+	return report
+}
+
+// factoryKinds returns the sorted string form of a component.Factories map's
+// keys (each of the Receivers/Processors/Exporters/Extensions maps is keyed
+// by configmodels.Type, a string type).
+func factoryKinds(m interface{}) []string {
+	v := reflect.ValueOf(m)
+	kinds := make([]string, 0, v.Len())
+	for _, key := range v.MapKeys() {
+		kinds = append(kinds, key.String())
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+func (r *Reporter) loadOrCreateInstallID() (string, error) {
+	path := filepath.Join(r.dataDir, installIDFile)
+
+	if b, err := ioutil.ReadFile(path); err == nil {
+		if id := strings.TrimSpace(string(b)); id != "" {
+			return id, nil
+		}
+	}
+
+	id := uuid.New().String()
+	if err := os.MkdirAll(r.dataDir, 0o755); err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(path, []byte(id), 0o644); err != nil {
+		return "", err
+	}
+	return id, nil
+}
@@ -0,0 +1,117 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package selftelemetry
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"go.opentelemetry.io/collector/component"
+)
+
+func TestDisabled(t *testing.T) {
+	devInfo := component.BinaryInfo{Version: "latest"}
+	releaseInfo := component.BinaryInfo{Version: "1.2.3"}
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		info    component.BinaryInfo
+		flag    bool
+		envVars map[string]string
+		want    bool
+	}{
+		{name: "enabled by default", info: releaseInfo, want: false},
+		{name: "dev version", info: devInfo, want: true},
+		{name: "-dev suffix version", info: component.BinaryInfo{Version: "1.2.3-dev"}, want: true},
+		{name: "cli flag", info: releaseInfo, flag: true, want: true},
+		{name: "config disabled", cfg: Config{Disabled: true}, info: releaseInfo, want: true},
+		{name: "DO_NOT_TRACK", info: releaseInfo, envVars: map[string]string{"DO_NOT_TRACK": "1"}, want: true},
+		{name: "OTELCOL_TELEMETRY=0", info: releaseInfo, envVars: map[string]string{"OTELCOL_TELEMETRY": "0"}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.envVars {
+				os.Setenv(k, v)
+				defer os.Unsetenv(k)
+			}
+			oldFlag := disableFlag
+			disableFlag = tt.flag
+			defer func() { disableFlag = oldFlag }()
+
+			assert.Equal(t, tt.want, disabled(tt.cfg, tt.info))
+		})
+	}
+}
+
+func TestNewDisabledReturnsNil(t *testing.T) {
+	r := New(Config{Disabled: true}, component.BinaryInfo{Version: "1.0.0"}, component.Factories{}, nil, "", zap.NewNop())
+	assert.Nil(t, r)
+
+	// All methods must be safe to call on the nil Reporter.
+	require.NoError(t, r.Start(nil))
+	r.Stop()
+}
+
+func TestLoadOrCreateInstallIDPersists(t *testing.T) {
+	dir := t.TempDir()
+	r := &Reporter{dataDir: dir}
+
+	id1, err := r.loadOrCreateInstallID()
+	require.NoError(t, err)
+	assert.NotEmpty(t, id1)
+
+	id2, err := r.loadOrCreateInstallID()
+	require.NoError(t, err)
+	assert.Equal(t, id1, id2)
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, installIDFile))
+	require.NoError(t, err)
+	assert.Equal(t, id1, string(b))
+}
+
+func TestBackoffDoublesAndCaps(t *testing.T) {
+	r := &Reporter{cfg: Config{Interval: 10 * time.Second}}
+
+	assert.Equal(t, 1*time.Second, r.backoff(1))
+	assert.Equal(t, 2*time.Second, r.backoff(2))
+	assert.Equal(t, 4*time.Second, r.backoff(3))
+	assert.Equal(t, 8*time.Second, r.backoff(4))
+	// Would be 16s uncapped, but must not exceed the configured interval.
+	assert.Equal(t, 10*time.Second, r.backoff(5))
+	assert.Equal(t, 10*time.Second, r.backoff(50))
+}
+
+func TestBuildReportIncludesFactoryKinds(t *testing.T) {
+	r := &Reporter{
+		installID: "test-id",
+		factories: component.Factories{},
+	}
+
+	report := r.buildReport()
+	assert.Equal(t, "test-id", report.InstallID)
+	assert.Empty(t, report.Receivers)
+	assert.Empty(t, report.Processors)
+	assert.Empty(t, report.Exporters)
+	assert.Empty(t, report.Extensions)
+}